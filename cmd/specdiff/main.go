@@ -0,0 +1,55 @@
+// Command specdiff compares two OpenAPI specs and exits with a non-zero status on breaking changes
+//
+//	specdiff [-breaking] old.yaml new.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+func main() {
+	breakingOnly := flag.Bool("breaking", false, "only print breaking changes")
+
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: specdiff [-breaking] old.(json|yaml) new.(json|yaml)")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := spec.LoadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	new, err := spec.LoadFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	changes := spec.Diff(old, new)
+
+	if *breakingOnly {
+		changes = changes.Breaking()
+	}
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if changes.HasBreaking() {
+		fmt.Fprintf(os.Stderr, "%d breaking change(s)\n", len(changes.Breaking()))
+		os.Exit(1)
+	}
+}
@@ -3,8 +3,6 @@ package main
 import (
 	"context"
 	"embed"
-	"encoding/json"
-	"fmt"
 	"github.com/hibiken/asynq"
 	"github.com/khvh/gwf/pkg/config"
 	"github.com/khvh/gwf/pkg/core/dto"
@@ -13,7 +11,6 @@ import (
 	"github.com/khvh/gwf/pkg/queue"
 	"github.com/khvh/gwf/pkg/router"
 	"github.com/labstack/echo/v4"
-	"github.com/rs/zerolog/log"
 	"time"
 )
 
@@ -27,81 +24,26 @@ var content embed.FS
 //go:embed ui/dist/*
 var ui embed.FS
 
-const (
-	TypeEmailDelivery = "email:deliver"
-	TypeImageResize   = "image:resize"
+// Tasks are defined once with their payload type and default options, which can be overridden at enqueue time
+var (
+	EmailDelivery = queue.NewTask[dto.EmailDeliveryPayload]("email:deliver")
+	ImageResize   = queue.NewTask[dto.ImageResizePayload]("image:resize", asynq.MaxRetry(5), asynq.Timeout(20*time.Minute), asynq.Retention(time.Hour))
 )
 
-type EmailDeliveryPayload struct {
-	UserID     int
-	TemplateID string
-}
-
-type ImageResizePayload struct {
-	SourceURL string
-}
-
-//----------------------------------------------
-// Write a function NewXXXTask to create a task.
-// A task consists of a type and a payload.
-//----------------------------------------------
-
-func NewEmailDeliveryTask(userID int, tmplID string) (*asynq.Task, error) {
-	payload, err := json.Marshal(EmailDeliveryPayload{UserID: userID, TemplateID: tmplID})
-	if err != nil {
-		return nil, err
-	}
-	return asynq.NewTask(TypeEmailDelivery, payload), nil
-}
-
-func NewImageResizeTask(src string) (*asynq.Task, error) {
-	payload, err := json.Marshal(ImageResizePayload{SourceURL: src})
-	if err != nil {
-		return nil, err
-	}
-	// task options can be passed to NewTask, which can be overridden at enqueue time.
-	return asynq.NewTask(TypeImageResize, payload, asynq.MaxRetry(5), asynq.Timeout(20*time.Minute)), nil
-}
-
-//---------------------------------------------------------------
-// Write a function HandleXXXTask to handle the input task.
-// Note that it satisfies the asynq.HandlerFunc interface.
-//
-// Handler doesn't need to be a function. You can define a type
-// that satisfies asynq.Handler interface. See examples below.
-//---------------------------------------------------------------
-
-func HandleEmailDeliveryTask(ctx context.Context, t *asynq.Task) error {
-	var p EmailDeliveryPayload
-	if err := json.Unmarshal(t.Payload(), &p); err != nil {
-		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
-	}
-	log.Trace().Msgf("Sending Email to User: user_id=%d, template_id=%s", p.UserID, p.TemplateID)
+func HandleEmailDeliveryTask(ctx context.Context, p dto.EmailDeliveryPayload) error {
+	logger.Ctx(ctx).Trace().Msgf("Sending Email to User: user_id=%d, template_id=%s", p.UserID, p.TemplateID)
 	// Email delivery code ...
 	return nil
 }
 
-// ImageProcessor implements asynq.Handler interface.
-type ImageProcessor struct {
-	// ... fields for struct
-}
-
-func (processor *ImageProcessor) ProcessTask(ctx context.Context, t *asynq.Task) error {
-	var p ImageResizePayload
-	if err := json.Unmarshal(t.Payload(), &p); err != nil {
-		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
-	}
-	log.Trace().Msgf("Resizing image: src=%s", p.SourceURL)
+func HandleImageResizeTask(ctx context.Context, p dto.ImageResizePayload) (dto.ImageResizeResult, error) {
+	logger.Ctx(ctx).Trace().Msgf("Resizing image: src=%s", p.SourceURL)
 	// Image resizing code ...
-	return nil
-}
-
-func NewImageProcessor() *ImageProcessor {
-	return &ImageProcessor{}
+	return dto.ImageResizeResult{URL: p.SourceURL}, nil
 }
 
 func main() {
-	if err := config.Autoload(); err != nil {
+	if err := config.Autoload(); err != nil && !gwf.Exporting() {
 		panic(err)
 	}
 
@@ -110,15 +52,13 @@ func main() {
 	gwf.
 		Create(content).
 		EnableTracing().
+		Validate().
 		Configure(func(e *echo.Echo) {
 			e.GET("/runtask", func(c echo.Context) error {
-				task, err := NewEmailDeliveryTask(42, "some:template:id")
-				if err != nil {
-					log.Fatal().Err(err)
+				if _, err := EmailDelivery.Enqueue(c.Request().Context(), dto.EmailDeliveryPayload{UserID: 42, TemplateID: "some:template:id"}); err != nil {
+					return err
 				}
 
-				queue.NewClient("127.0.0.1:6379").Add(task)
-
 				return c.JSON(200, true)
 			})
 		}).
@@ -128,23 +68,33 @@ func main() {
 				Group("asd").
 				Prefix("/api/v1").
 				Register(
-					router.Get[dto.Sample]("", h),
+					router.Get[dto.Sample]("", h).OperationID("listSamples"),
 					router.
-						Get[dto.Sample]("/some/:id/path/:subId", h).Query("lol").Header("lmao").Summary("Testing summary").Description("kek").Tags("1"),
+						Get[dto.Sample]("/some/:id/path/:subId", h).OperationID("getSample").Query("lol").Header("lmao").Summary("Testing summary").Description("kek").Tags("1"),
 					router.
-						Delete[dto.Sample]("/some/:id/path/:subId", h).Tags("1"),
+						Delete[dto.Sample]("/some/:id/path/:subId", h).OperationID("deleteSample").Tags("1"),
 					router.
-						Post[dto.Sample, dto.Sample]("/some/:id/path", h).Tags("1").Query("lol"),
+						Post[dto.Sample, dto.Sample]("/some/:id/path", h).OperationID("createSample").Tags("1").Query("lol"),
 					router.
-						Put[dto.Sample, dto.Sample]("/some/:id/path/:subId", h).Tags("1"),
+						Put[dto.Sample, dto.Sample]("/some/:id/path/:subId", h).OperationID("replaceSample").Tags("1"),
 					router.
-						Patch[dto.Sample, dto.Sample]("/some/:id/path/:subId", h).Tags("1"),
+						Patch[dto.Sample, dto.Sample]("/some/:id/path/:subId", h).OperationID("updateSample").Tags("1"),
+					router.Async[dto.ImageResizePayload, dto.ImageResizeResult]("/images", ImageResize).OperationID("resizeImage").Summary("Resize an image"),
 				),
 		).
 		Queue(func(q *queue.Queue) {
-			q.
-				AddHandlerFunc(TypeEmailDelivery, HandleEmailDeliveryTask).
-				AddHandler(TypeImageResize, NewImageProcessor())
+			q.Register(
+				EmailDelivery.Handle(HandleEmailDeliveryTask),
+				queue.HandleResult(ImageResize, HandleImageResizeTask),
+			)
+
+			q.Policy(
+				EmailDelivery.Name(),
+				queue.WithBackoff(queue.Exponential(time.Second, time.Hour)),
+				queue.OnArchived(func(ctx context.Context, f *queue.Failure) {
+					logger.Ctx(ctx).Error().Err(f.Err).Str("class", f.Class).Msgf("Email delivery %s is archived", f.ID)
+				}),
+			)
 		}).
 		Run()
 }
\ No newline at end of file
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// Client is an HTTP client for gwf services, generated clients embed it
+type Client struct {
+	baseURL string
+	http    *http.Client
+	header  http.Header
+	retries int
+	backoff time.Duration
+	editors []func(req *http.Request) error
+}
+
+// Option configures a Client
+type Option func(c *Client)
+
+// WithHTTPClient sets the underlying http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithHeader adds a header to every request
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.header.Add(name, value)
+	}
+}
+
+// WithBearer authenticates every request with a bearer token
+func WithBearer(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithRetries retries idempotent requests on network errors, 429 and 502-504, waiting backoff, then twice as long each time
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithRequestEditor changes every request before it is sent, e.g. to add a fresh token
+func WithRequestEditor(fn func(req *http.Request) error) Option {
+	return func(c *Client) {
+		c.editors = append(c.editors, fn)
+	}
+}
+
+// New creates a client for the service at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+		header:  http.Header{},
+		retries: 2,
+		backoff: 100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Error is returned for non-2xx responses, the body is decoded into spec.Error when possible
+type Error struct {
+	Status  int
+	Body    []byte
+	Details spec.Error
+}
+
+func (e *Error) Error() string {
+	if e.Details.Msg != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Details.Msg)
+	}
+
+	return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+}
+
+// Request describes a call made by a generated client
+type Request struct {
+	Method      string
+	Path        string
+	Query       url.Values
+	Header      http.Header
+	Body        interface{}
+	ContentType string
+}
+
+// Do sends the request and decodes a successful response into T
+func Do[T any](ctx context.Context, c *Client, r Request) (T, error) {
+	var out T
+
+	res, err := c.send(ctx, r)
+	if err != nil {
+		return out, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return out, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		e := &Error{
+			Status: res.StatusCode,
+			Body:   body,
+		}
+
+		_ = json.Unmarshal(body, &e.Details)
+
+		return out, e
+	}
+
+	if len(body) == 0 {
+		return out, nil
+	}
+
+	switch target := any(&out).(type) {
+	case *[]byte:
+		*target = body
+	case *string:
+		*target = string(body)
+	default:
+		if err := json.Unmarshal(body, &out); err != nil {
+			return out, fmt.Errorf("decoding %s %s response: %w", r.Method, r.Path, err)
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Client) send(ctx context.Context, r Request) (*http.Response, error) {
+	var (
+		body        []byte
+		contentType = r.ContentType
+	)
+
+	switch b := r.Body.(type) {
+	case nil:
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		body = encoded
+		contentType = spec.MIMEJSON
+	}
+
+	if contentType == "" && body != nil {
+		contentType = spec.MIMEOctetStream
+	}
+
+	u := c.baseURL + r.Path
+
+	if len(r.Query) > 0 {
+		u += "?" + r.Query.Encode()
+	}
+
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.Method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range c.header {
+			req.Header[name] = append([]string{}, values...)
+		}
+
+		for name, values := range r.Header {
+			req.Header[name] = append([]string{}, values...)
+		}
+
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		req.Header.Set("Accept", spec.MIMEJSON)
+
+		for _, edit := range c.editors {
+			if err := edit(req); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := c.http.Do(req)
+
+		if attempt >= c.retries || !idempotent(r.Method) || ctx.Err() != nil || !retryable(res, err) {
+			return res, err
+		}
+
+		wait := backoff
+
+		if res != nil {
+			if after, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(after) * time.Second
+			}
+
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+	}
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// PathParam escapes a value for use in a path
+func PathParam(v interface{}) string {
+	return url.PathEscape(fmt.Sprint(v))
+}
+
+// SetQuery sets a query param, creating the values if needed
+func SetQuery(q url.Values, name, value string) url.Values {
+	if q == nil {
+		q = url.Values{}
+	}
+
+	q.Set(name, value)
+
+	return q
+}
+
+// SetHeader sets a header, creating the header if needed
+func SetHeader(h http.Header, name, value string) http.Header {
+	if h == nil {
+		h = http.Header{}
+	}
+
+	h.Set(name, value)
+
+	return h
+}
+
+// AddCookie adds a cookie to the Cookie header
+func AddCookie(h http.Header, name, value string) http.Header {
+	if h == nil {
+		h = http.Header{}
+	}
+
+	h.Add("Cookie", (&http.Cookie{Name: name, Value: value}).String())
+
+	return h
+}
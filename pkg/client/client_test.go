@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID int `json:"id"`
+}
+
+// failing responds with the given statuses in turn, then with an item
+func failing(calls *int32, header http.Header, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1)) - 1
+
+		if n < len(statuses) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+
+			w.WriteHeader(statuses[n])
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	}))
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		calls    int32
+		err      int
+	}{
+		{"429 retried", http.MethodGet, []int{http.StatusTooManyRequests}, 2, 0},
+		{"502 retried", http.MethodGet, []int{http.StatusBadGateway}, 2, 0},
+		{"503 retried", http.MethodPut, []int{http.StatusServiceUnavailable}, 2, 0},
+		{"504 retried", http.MethodDelete, []int{http.StatusGatewayTimeout}, 2, 0},
+		{"500 not retried", http.MethodGet, []int{http.StatusInternalServerError}, 1, http.StatusInternalServerError},
+		{"POST not retried", http.MethodPost, []int{http.StatusServiceUnavailable}, 1, http.StatusServiceUnavailable},
+		{"PATCH not retried", http.MethodPatch, []int{http.StatusServiceUnavailable}, 1, http.StatusServiceUnavailable},
+		{"retries exhausted", http.MethodGet, []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 3, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+
+			srv := failing(&calls, nil, tt.statuses...)
+			defer srv.Close()
+
+			c := New(srv.URL, WithRetries(2, time.Millisecond))
+
+			got, err := Do[item](context.Background(), c, Request{Method: tt.method, Path: "/items"})
+
+			if calls := atomic.LoadInt32(&calls); calls != tt.calls {
+				t.Errorf("server called %d times, want %d", calls, tt.calls)
+			}
+
+			if tt.err == 0 {
+				if err != nil || got.ID != 1 {
+					t.Errorf("got %v, %v, want the item", got, err)
+				}
+
+				return
+			}
+
+			var e *Error
+
+			if !errors.As(err, &e) || e.Status != tt.err {
+				t.Errorf("got %v, want a %d error", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var calls int32
+
+	srv := failing(&calls, http.Header{"Retry-After": []string{"0"}}, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	// The backoff is longer than the test timeout, only Retry-After lets the retry happen in time
+	c := New(srv.URL, WithRetries(1, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := Do[item](ctx, c, Request{Method: http.MethodGet, Path: "/items"}); err != nil {
+		t.Fatalf("got %v, want the retry to wait for Retry-After", err)
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Errorf("server called %d times, want 2", calls)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+		code    string
+		err     string
+	}{
+		{"spec error", `{"code":"not_found","message":"no such item"}`, "no such item", "not_found", "404 Not Found: no such item"},
+		{"plain text", "not found", "", "", "404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := Do[item](context.Background(), New(srv.URL), Request{Method: http.MethodGet, Path: "/items/1"})
+
+			var e *Error
+
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want an *Error", err)
+			}
+
+			if e.Status != http.StatusNotFound || string(e.Body) != tt.body {
+				t.Errorf("got %d %q, want 404 %q", e.Status, e.Body, tt.body)
+			}
+
+			if e.Details.Msg != tt.message || e.Details.Code != tt.code {
+				t.Errorf("got details %+v, want code %q and message %q", e.Details, tt.code, tt.message)
+			}
+
+			if e.Error() != tt.err {
+				t.Errorf("got %q, want %q", e.Error(), tt.err)
+			}
+		})
+	}
+}
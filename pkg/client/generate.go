@@ -0,0 +1,491 @@
+package client
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// Generate writes a Go client package with one typed method per operation, using the request and response types of the routes
+func Generate(w io.Writer, pkg string, ops []spec.Operation) error {
+	g := &generator{
+		imports: map[string]string{
+			"context":                        "context",
+			"github.com/khvh/gwf/pkg/client": "client",
+		},
+		aliases: map[string]bool{"context": true, "client": true},
+	}
+
+	sorted := append([]spec.Operation{}, ops...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	seen := map[string]spec.Operation{}
+
+	for _, op := range sorted {
+		name := exported(op.ID)
+
+		if existing, ok := seen[name]; ok {
+			return fmt.Errorf("operations %s %s and %s %s both generate method %s", existing.Method, existing.Path, op.Method, op.Path, name)
+		}
+
+		seen[name] = op
+
+		if err := g.operation(name, op); err != nil {
+			return fmt.Errorf("%s %s: %w", op.Method, op.Path, err)
+		}
+	}
+
+	var b strings.Builder
+
+	b.WriteString("// Code generated by gwf. DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkg + "\n\n")
+	b.WriteString("import (\n")
+
+	std := true
+
+	for _, p := range sortedKeys(g.imports) {
+		alias := g.imports[p]
+
+		// Standard library imports come first, separated from the rest
+		if std && strings.Contains(strings.Split(p, "/")[0], ".") {
+			std = false
+
+			b.WriteString("\n")
+		}
+
+		if alias == path.Base(p) {
+			fmt.Fprintf(&b, "\t%q\n", p)
+		} else {
+			fmt.Fprintf(&b, "\t%s %q\n", alias, p)
+		}
+	}
+
+	b.WriteString(")\n\n")
+	b.WriteString("// Client calls the API with typed methods\n")
+	b.WriteString("type Client struct {\n\t*client.Client\n}\n\n")
+	b.WriteString("// New creates a client for the API at baseURL\n")
+	b.WriteString("func New(baseURL string, opts ...client.Option) *Client {\n")
+	b.WriteString("\treturn &Client{Client: client.New(baseURL, opts...)}\n}\n")
+	b.WriteString(g.body.String())
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("formatting generated client: %w", err)
+	}
+
+	_, err = w.Write(src)
+
+	return err
+}
+
+type generator struct {
+	imports map[string]string
+	aliases map[string]bool
+	body    strings.Builder
+}
+
+func (g *generator) operation(name string, op spec.Operation) error {
+	var (
+		args     = []string{"ctx context.Context"}
+		extra    []spec.Param
+		bodyType string
+		resType  = "[]byte"
+		ct       string
+		err      error
+	)
+
+	for _, p := range pathParams(op.Path) {
+		args = append(args, unexported(p)+" string")
+	}
+
+	for _, p := range op.Params {
+		if p.In != "path" {
+			extra = append(extra, p)
+		}
+	}
+
+	if op.Request != nil {
+		if ct, err = contentType(op); err != nil {
+			return err
+		}
+
+		if bodyType, err = g.typeExpr(op.Request); err != nil {
+			return err
+		}
+
+		args = append(args, "body "+bodyType)
+	}
+
+	if len(extra) > 0 {
+		args = append(args, "params "+name+"Params")
+	}
+
+	if op.Response != nil {
+		if resType, err = g.typeExpr(op.Response); err != nil {
+			return err
+		}
+	}
+
+	b := &g.body
+
+	if len(extra) > 0 {
+		fmt.Fprintf(b, "\n// %sParams holds the query, header and cookie params of %s\n", name, name)
+		fmt.Fprintf(b, "type %sParams struct {\n", name)
+
+		for _, p := range extra {
+			fmt.Fprintf(b, "\t%s %s\n", exported(p.Name), paramType(p.Type))
+		}
+
+		b.WriteString("}\n")
+	}
+
+	fmt.Fprintf(b, "\n// %s calls %s %s", name, op.Method, op.Path)
+
+	if op.Summary != "" {
+		fmt.Fprintf(b, ", %s", op.Summary)
+	}
+
+	b.WriteString("\n")
+
+	if op.Deprecated {
+		b.WriteString("//\n// Deprecated: the operation is deprecated.\n")
+	}
+
+	if op.Response != nil {
+		fmt.Fprintf(b, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), resType)
+	} else {
+		fmt.Fprintf(b, "func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+	}
+
+	fmt.Fprintf(b, "\treq := client.Request{\n\t\tMethod: %q,\n\t\tPath: %s,\n", op.Method, pathExpr(op.Path))
+
+	if op.Request != nil {
+		b.WriteString("\t\tBody: body,\n")
+
+		if ct != "" {
+			fmt.Fprintf(b, "\t\tContentType: %q,\n", ct)
+		}
+	}
+
+	b.WriteString("\t}\n")
+
+	for _, p := range extra {
+		field := "params." + exported(p.Name)
+		value := "fmt.Sprint(" + field + ")"
+
+		if paramType(p.Type) == "string" {
+			value = field
+		} else {
+			g.imports["fmt"] = "fmt"
+		}
+
+		setter := ""
+
+		switch p.In {
+		case "query":
+			setter = fmt.Sprintf("req.Query = client.SetQuery(req.Query, %q, %s)", p.Name, value)
+		case "header":
+			setter = fmt.Sprintf("req.Header = client.SetHeader(req.Header, %q, %s)", p.Name, value)
+		case "cookie":
+			setter = fmt.Sprintf("req.Header = client.AddCookie(req.Header, %q, %s)", p.Name, value)
+		}
+
+		if p.Required {
+			fmt.Fprintf(b, "\t%s\n", setter)
+		} else {
+			fmt.Fprintf(b, "\tif %s != %s {\n\t\t%s\n\t}\n", field, zero(paramType(p.Type)), setter)
+		}
+	}
+
+	if op.Response != nil {
+		fmt.Fprintf(b, "\n\treturn client.Do[%s](ctx, c.Client, req)\n}\n", resType)
+	} else {
+		b.WriteString("\n\t_, err := client.Do[[]byte](ctx, c.Client, req)\n\n\treturn err\n}\n")
+	}
+
+	return nil
+}
+
+// typeExpr renders a Go expression for t, importing its package
+func (g *generator) typeExpr(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), nil
+		}
+
+		if t.PkgPath() == "main" {
+			return "", fmt.Errorf("type %s is declared in package main and cannot be imported, move it to its own package", t)
+		}
+
+		name := t.Name()
+
+		if base := strings.SplitN(name, "[", 2)[0]; !token.IsExported(base) {
+			return "", fmt.Errorf("type %s is unexported and cannot be referenced by the client, export it", t)
+		}
+
+		// Instantiated generic types are named e.g. TaskStatus[github.com/khvh/gwf/pkg/core/dto.Export]
+		if i := strings.IndexByte(name, '['); i >= 0 {
+			args, err := g.qualify(name[i:])
+			if err != nil {
+				return "", fmt.Errorf("type %s: %w", t, err)
+			}
+
+			name = name[:i] + args
+		}
+
+		return g.importAlias(t.PkgPath()) + "." + name, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := g.typeExpr(t.Elem())
+
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := g.typeExpr(t.Elem())
+
+		return "[]" + elem, err
+	case reflect.Array:
+		elem, err := g.typeExpr(t.Elem())
+
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), err
+	case reflect.Map:
+		key, err := g.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+
+		elem, err := g.typeExpr(t.Elem())
+
+		return "map[" + key + "]" + elem, err
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+	}
+
+	return "", fmt.Errorf("type %s is not supported", t)
+}
+
+// qualify replaces the package paths of the type names in the type arguments of a generic type with import aliases
+func (g *generator) qualify(args string) (string, error) {
+	var (
+		b     strings.Builder
+		start int
+	)
+
+	ident := func(s string) error {
+		i := strings.LastIndexByte(s, '.')
+		if i < 0 {
+			b.WriteString(s)
+
+			return nil
+		}
+
+		if s[:i] == "main" {
+			return fmt.Errorf("type argument %s is declared in package main and cannot be imported, move it to its own package", s)
+		}
+
+		if !token.IsExported(s[i+1:]) {
+			return fmt.Errorf("type argument %s is unexported and cannot be referenced by the client, export it", s)
+		}
+
+		b.WriteString(g.importAlias(s[:i]) + "." + s[i+1:])
+
+		return nil
+	}
+
+	for i, r := range args {
+		if !strings.ContainsRune("[](),* ", r) {
+			continue
+		}
+
+		if err := ident(args[start:i]); err != nil {
+			return "", err
+		}
+
+		b.WriteRune(r)
+		start = i + 1
+	}
+
+	if err := ident(args[start:]); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func (g *generator) importAlias(pkgPath string) string {
+	if alias, ok := g.imports[pkgPath]; ok {
+		return alias
+	}
+
+	base := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, path.Base(pkgPath))
+
+	alias := base
+
+	for i := 2; g.aliases[alias]; i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+
+	g.imports[pkgPath] = alias
+	g.aliases[alias] = true
+
+	return alias
+}
+
+// contentType returns the content type to send a request body with, "" for JSON
+func contentType(op spec.Operation) (string, error) {
+	raw := op.Request.Kind() == reflect.String ||
+		(op.Request.Kind() == reflect.Slice && op.Request.Elem().Kind() == reflect.Uint8)
+
+	if raw {
+		if len(op.Consumes) > 0 {
+			return op.Consumes[0], nil
+		}
+
+		return spec.MIMEOctetStream, nil
+	}
+
+	if len(op.Consumes) == 0 {
+		return "", nil
+	}
+
+	for _, ct := range op.Consumes {
+		if ct == spec.MIMEJSON {
+			return "", nil
+		}
+	}
+
+	return "", fmt.Errorf("request bodies of type %s are not supported", strings.Join(op.Consumes, ", "))
+}
+
+func pathParams(p string) []string {
+	var params []string
+
+	for _, segment := range strings.Split(p, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, strings.Trim(segment, "{}"))
+		}
+	}
+
+	return params
+}
+
+// pathExpr turns /users/{id} into "/users/" + client.PathParam(id)
+func pathExpr(p string) string {
+	var (
+		parts   []string
+		literal strings.Builder
+	)
+
+	for i, segment := range strings.Split(p, "/") {
+		if i > 0 {
+			literal.WriteString("/")
+		}
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			parts = append(parts, fmt.Sprintf("%q", literal.String()))
+			parts = append(parts, "client.PathParam("+unexported(strings.Trim(segment, "{}"))+")")
+			literal.Reset()
+
+			continue
+		}
+
+		literal.WriteString(segment)
+	}
+
+	if literal.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", literal.String()))
+	}
+
+	return strings.Join(parts, " + ")
+}
+
+func paramType(typ string) string {
+	switch typ {
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+
+	return "string"
+}
+
+func zero(typ string) string {
+	switch typ {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	}
+
+	return "0"
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exported turns an operationId or param name into an exported identifier, e.g. X-Request-ID into XRequestID
+func exported(s string) string {
+	var b strings.Builder
+
+	for _, word := range words(s) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	if b.Len() == 0 || unicode.IsDigit(rune(b.String()[0])) {
+		return "X" + b.String()
+	}
+
+	return b.String()
+}
+
+func unexported(s string) string {
+	name := exported(s)
+	name = strings.ToLower(name[:1]) + name[1:]
+
+	switch name {
+	case "ctx", "body", "params", "req", "c", "err", "client", "type", "func", "var", "range", "map", "chan", "go",
+		"default", "select", "case", "interface", "struct", "package", "import", "return", "break", "continue",
+		"const", "defer", "else", "fallthrough", "for", "goto", "if", "switch":
+		return name + "Param"
+	}
+
+	return name
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
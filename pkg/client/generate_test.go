@@ -0,0 +1,112 @@
+package client
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khvh/gwf/pkg/core/dto"
+	"github.com/khvh/gwf/pkg/queue"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestTypeExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		want    string
+		imports []string
+		err     string
+	}{
+		{name: "builtin", typ: reflect.TypeOf(""), want: "string"},
+		{name: "named", typ: reflect.TypeOf(dto.Sample{}), want: "dto.Sample", imports: []string{"github.com/khvh/gwf/pkg/core/dto"}},
+		{name: "slice of pointers", typ: reflect.TypeOf([]*dto.Sample{}), want: "[]*dto.Sample"},
+		{name: "map", typ: reflect.TypeOf(map[string]time.Time{}), want: "map[string]time.Time", imports: []string{"time"}},
+		{
+			name:    "generic",
+			typ:     reflect.TypeOf(queue.TaskStatus[dto.ImageResizeResult]{}),
+			want:    "queue.TaskStatus[dto.ImageResizeResult]",
+			imports: []string{"github.com/khvh/gwf/pkg/queue", "github.com/khvh/gwf/pkg/core/dto"},
+		},
+		{
+			name: "generic of composite types",
+			typ:  reflect.TypeOf(Page[map[string][]*queue.TaskStatus[dto.Sample]]{}),
+			want: "client.Page[map[string][]*queue.TaskStatus[dto.Sample]]",
+		},
+		{
+			name: "unexported",
+			typ:  reflect.TypeOf(page[dto.Sample]{}),
+			err:  "type client.page[github.com/khvh/gwf/pkg/core/dto.Sample] is unexported",
+		},
+		{
+			name: "unexported type argument",
+			typ:  reflect.TypeOf(queue.TaskStatus[page[dto.Sample]]{}),
+			err:  "type argument github.com/khvh/gwf/pkg/client.page is unexported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &generator{imports: map[string]string{}, aliases: map[string]bool{}}
+
+			got, err := g.typeExpr(tt.typ)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+
+			for _, p := range tt.imports {
+				if _, ok := g.imports[p]; !ok {
+					t.Errorf("%s is not imported", p)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateGeneric(t *testing.T) {
+	var b strings.Builder
+
+	err := Generate(&b, "api", []spec.Operation{{
+		ID:          "resizeImageStatus",
+		Method:      http.MethodGet,
+		Path:        "/images/{id}",
+		Params:      []spec.Param{{Name: "id", In: "path", Required: true, Type: "string"}},
+		Response:    reflect.TypeOf(queue.TaskStatus[dto.ImageResizeResult]{}),
+		SuccessCode: http.StatusOK,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`"github.com/khvh/gwf/pkg/core/dto"`,
+		`"github.com/khvh/gwf/pkg/queue"`,
+		"(queue.TaskStatus[dto.ImageResizeResult], error)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("generated client has no %s:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// tsRuntime is the fetch-based request helper shared by the generated functions
+const tsRuntime = `export class ApiError extends Error {
+  constructor(
+    public status: number,
+    public code?: string,
+    public data?: Record<string, unknown>,
+    public body?: string,
+  ) {
+    super(code ? status + " " + code : String(status));
+  }
+}
+
+export interface RequestOptions {
+  baseUrl?: string;
+  headers?: Record<string, string>;
+  signal?: AbortSignal;
+  fetch?: typeof fetch;
+}
+
+let defaults: RequestOptions = { baseUrl: "" };
+
+/** configure sets options used by every request, e.g. the base URL or an Authorization header */
+export function configure(options: RequestOptions) {
+  defaults = { ...defaults, ...options, headers: { ...defaults.headers, ...options.headers } };
+}
+
+type Query = Record<string, string | number | boolean | undefined>;
+
+interface Call {
+  query?: Query;
+  headers?: Query;
+  body?: unknown;
+  contentType?: string;
+  response?: "json" | "text" | "blob" | "none";
+}
+
+async function request<T>(method: string, path: string, call: Call, options: RequestOptions = {}): Promise<T> {
+  const opts = { ...defaults, ...options, headers: { ...defaults.headers, ...options.headers } };
+  const query = new URLSearchParams();
+
+  Object.entries(call.query ?? {}).forEach(([name, value]) => {
+    if (value !== undefined) {
+      query.append(name, String(value));
+    }
+  });
+
+  const headers: Record<string, string> = { Accept: "application/json", ...opts.headers };
+
+  Object.entries(call.headers ?? {}).forEach(([name, value]) => {
+    if (value !== undefined) {
+      headers[name] = String(value);
+    }
+  });
+
+  let body: BodyInit | undefined;
+
+  if (call.body !== undefined) {
+    if (call.contentType === "application/json") {
+      headers["Content-Type"] = call.contentType;
+      body = JSON.stringify(call.body);
+    } else {
+      if (call.contentType && !(call.body instanceof FormData) && !(call.body instanceof URLSearchParams)) {
+        headers["Content-Type"] = call.contentType;
+      }
+
+      body = call.body as BodyInit;
+    }
+  }
+
+  const qs = query.toString();
+  const res = await (opts.fetch ?? fetch)((opts.baseUrl ?? "") + path + (qs ? "?" + qs : ""), {
+    method,
+    headers,
+    body,
+    signal: opts.signal,
+  });
+
+  if (!res.ok) {
+    const text = await res.text();
+    let err: { code?: string; message?: string; data?: Record<string, unknown> } = {};
+
+    try {
+      err = JSON.parse(text);
+    } catch {
+      // not a spec.Error body
+    }
+
+    const e = new ApiError(res.status, err.code, err.data, text);
+
+    if (err.message) {
+      e.message = err.message;
+    }
+
+    throw e;
+  }
+
+  switch (call.response) {
+    case "none":
+      return undefined as T;
+    case "text":
+      return (await res.text()) as T;
+    case "blob":
+      return (await res.blob()) as T;
+  }
+
+  const text = await res.text();
+
+  return (text ? JSON.parse(text) : undefined) as T;
+}
+`
+
+var tsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// GenerateTypeScript writes TypeScript types for all schemas of the spec and a fetch-based function per operation
+func GenerateTypeScript(w io.Writer, s *openapi3.Spec) error {
+	var b strings.Builder
+
+	b.WriteString("// Code generated by gwf. DO NOT EDIT.\n/* eslint-disable */\n\n")
+	b.WriteString(tsRuntime)
+
+	if s.Components != nil && s.Components.Schemas != nil {
+		schemas := s.Components.Schemas.MapOfSchemaOrRefValues
+
+		for _, name := range sortedSchemaNames(schemas) {
+			schema := schemas[name]
+
+			b.WriteString("\n")
+
+			if schema.Schema != nil && schema.Schema.Description != nil && *schema.Schema.Description != "" {
+				fmt.Fprintf(&b, "/** %s */\n", tsComment(*schema.Schema.Description))
+			}
+
+			if schema.Schema != nil && schema.Schema.Enum == nil && len(schema.Schema.Properties) > 0 {
+				fmt.Fprintf(&b, "export interface %s %s\n", tsTypeName(name), tsType(&schema, 0))
+			} else {
+				fmt.Fprintf(&b, "export type %s = %s;\n", tsTypeName(name), tsType(&schema, 0))
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(s.Paths.MapOfPathItemValues))
+
+	for p := range s.Paths.MapOfPathItemValues {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := s.Paths.MapOfPathItemValues[p]
+		methods := make([]string, 0, len(item.MapOfOperationValues))
+
+		for method := range item.MapOfOperationValues {
+			methods = append(methods, method)
+		}
+
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			op := item.MapOfOperationValues[method]
+
+			if err := tsOperation(&b, strings.ToUpper(method), p, &op); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func tsOperation(b *strings.Builder, method, p string, op *openapi3.Operation) error {
+	name := ""
+	if op.ID != nil {
+		name = tsUnexported(*op.ID)
+	}
+
+	if name == "" {
+		name = tsUnexported(strings.ToLower(method) + " " + p)
+	}
+
+	var (
+		args     []string
+		fields   []string
+		query    []string
+		headers  []string
+		required bool
+	)
+
+	pathExpr := p
+
+	for _, ref := range op.Parameters {
+		param := ref.Parameter
+		if param == nil {
+			continue
+		}
+
+		typ := tsType(param.Schema, 1)
+		optional := param.Required == nil || !*param.Required
+
+		switch param.In {
+		case openapi3.ParameterInPath:
+			arg := tsUnexported(param.Name)
+
+			args = append(args, arg+": "+typ)
+			pathExpr = strings.ReplaceAll(pathExpr, "{"+param.Name+"}", "${encodeURIComponent(String("+arg+"))}")
+
+			continue
+		case openapi3.ParameterInQuery:
+			query = append(query, fmt.Sprintf("%s: params[%q]", tsKey(param.Name), param.Name))
+		case openapi3.ParameterInHeader:
+			headers = append(headers, fmt.Sprintf("%s: params[%q]", tsKey(param.Name), param.Name))
+		default:
+			// Cookies are sent by the browser
+			continue
+		}
+
+		if optional {
+			fields = append(fields, fmt.Sprintf("%s?: %s", tsKey(param.Name), typ))
+		} else {
+			fields = append(fields, fmt.Sprintf("%s: %s", tsKey(param.Name), typ))
+			required = true
+		}
+	}
+
+	call := []string{}
+
+	if op.RequestBody != nil && op.RequestBody.RequestBody != nil {
+		contentType, typ := tsBody(op.RequestBody.RequestBody.Content)
+
+		args = append(args, "body: "+typ)
+		call = append(call, "body", fmt.Sprintf("contentType: %q", contentType))
+	}
+
+	if len(fields) > 0 {
+		arg := "params: { " + strings.Join(fields, "; ") + " }"
+
+		if !required {
+			arg += " = {}"
+		}
+
+		args = append(args, arg)
+	}
+
+	if len(query) > 0 {
+		call = append(call, "query: { "+strings.Join(query, ", ")+" }")
+	}
+
+	if len(headers) > 0 {
+		call = append(call, "headers: { "+strings.Join(headers, ", ")+" }")
+	}
+
+	resType, response := tsResponse(op.Responses)
+
+	if response != "json" {
+		call = append(call, fmt.Sprintf("response: %q", response))
+	}
+
+	args = append(args, "options?: RequestOptions")
+
+	summary := method + " " + p
+
+	if op.Summary != nil && *op.Summary != "" {
+		summary += " - " + tsComment(*op.Summary)
+	}
+
+	if op.Deprecated != nil && *op.Deprecated {
+		summary += "\n * @deprecated"
+	}
+
+	fmt.Fprintf(b, "\n/** %s */\n", summary)
+	fmt.Fprintf(b, "export function %s(%s): Promise<%s> {\n", name, strings.Join(args, ", "), resType)
+	callExpr := "{}"
+
+	if len(call) > 0 {
+		callExpr = "{ " + strings.Join(call, ", ") + " }"
+	}
+
+	fmt.Fprintf(b, "  return request<%s>(%q, `%s`, %s, options);\n}\n", resType, method, pathExpr, callExpr)
+
+	return nil
+}
+
+// tsBody picks the content type a request body is sent with, JSON when available
+func tsBody(content map[string]openapi3.MediaType) (string, string) {
+	if media, ok := content[spec.MIMEJSON]; ok {
+		return spec.MIMEJSON, tsType(media.Schema, 0)
+	}
+
+	for _, contentType := range sortedMediaTypes(content) {
+		switch contentType {
+		case spec.MIMEForm:
+			return contentType, "URLSearchParams"
+		case spec.MIMEMultipart:
+			return contentType, "FormData"
+		default:
+			return contentType, "Blob | string"
+		}
+	}
+
+	return spec.MIMEJSON, "unknown"
+}
+
+// tsResponse returns the type of the first successful response and how to read it
+func tsResponse(responses openapi3.Responses) (string, string) {
+	codes := make([]string, 0, len(responses.MapOfResponseOrRefValues))
+
+	for code := range responses.MapOfResponseOrRefValues {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+
+		res := responses.MapOfResponseOrRefValues[code].Response
+
+		if status, _ := strconv.Atoi(code); status == http.StatusNoContent || res == nil || len(res.Content) == 0 {
+			return "void", "none"
+		}
+
+		if media, ok := res.Content[spec.MIMEJSON]; ok {
+			return tsType(media.Schema, 0), "json"
+		}
+
+		for _, contentType := range sortedMediaTypes(res.Content) {
+			if strings.HasPrefix(contentType, "text/") || contentType == spec.MIMEXML {
+				return "string", "text"
+			}
+
+			return "Blob", "blob"
+		}
+	}
+
+	return "void", "none"
+}
+
+// tsType renders a schema as a TypeScript type, depth is the indentation of inline objects
+func tsType(ref *openapi3.SchemaOrRef, depth int) string {
+	if ref == nil {
+		return "unknown"
+	}
+
+	if ref.SchemaReference != nil {
+		return tsTypeName(strings.TrimPrefix(ref.SchemaReference.Ref, "#/components/schemas/"))
+	}
+
+	s := ref.Schema
+	if s == nil {
+		return "unknown"
+	}
+
+	typ := tsSchemaType(s, depth)
+
+	if s.Nullable != nil && *s.Nullable {
+		typ += " | null"
+	}
+
+	return typ
+}
+
+func tsSchemaType(s *openapi3.Schema, depth int) string {
+	if len(s.Enum) > 0 {
+		values := make([]string, 0, len(s.Enum))
+
+		for _, v := range s.Enum {
+			values = append(values, tsLiteral(v))
+		}
+
+		return strings.Join(values, " | ")
+	}
+
+	if len(s.AllOf) > 0 {
+		return tsJoin(s.AllOf, " & ", depth)
+	}
+
+	if len(s.OneOf) > 0 {
+		return tsJoin(s.OneOf, " | ", depth)
+	}
+
+	if len(s.AnyOf) > 0 {
+		return tsJoin(s.AnyOf, " | ", depth)
+	}
+
+	typ := ""
+	if s.Type != nil {
+		typ = string(*s.Type)
+	}
+
+	switch typ {
+	case "string":
+		if s.Format != nil && *s.Format == "binary" {
+			return "Blob"
+		}
+
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := tsType(s.Items, depth)
+
+		if strings.ContainsAny(item, "|&") {
+			return "(" + item + ")[]"
+		}
+
+		return item + "[]"
+	}
+
+	if len(s.Properties) > 0 {
+		return tsObject(s, depth)
+	}
+
+	if s.AdditionalProperties != nil && s.AdditionalProperties.SchemaOrRef != nil {
+		return "Record<string, " + tsType(s.AdditionalProperties.SchemaOrRef, depth) + ">"
+	}
+
+	if typ == "object" {
+		return "Record<string, unknown>"
+	}
+
+	return "unknown"
+}
+
+func tsObject(s *openapi3.Schema, depth int) string {
+	required := map[string]bool{}
+
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(s.Properties))
+
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	indent := strings.Repeat("  ", depth+1)
+
+	var b strings.Builder
+
+	b.WriteString("{\n")
+
+	for _, name := range names {
+		prop := s.Properties[name]
+		optional := "?"
+
+		if required[name] {
+			optional = ""
+		}
+
+		if prop.Schema != nil && prop.Schema.Description != nil && *prop.Schema.Description != "" {
+			fmt.Fprintf(&b, "%s/** %s */\n", indent, tsComment(*prop.Schema.Description))
+		}
+
+		fmt.Fprintf(&b, "%s%s%s: %s;\n", indent, tsKey(name), optional, tsType(&prop, depth+1))
+	}
+
+	b.WriteString(strings.Repeat("  ", depth) + "}")
+
+	return b.String()
+}
+
+func tsJoin(refs []openapi3.SchemaOrRef, sep string, depth int) string {
+	types := make([]string, 0, len(refs))
+
+	for i := range refs {
+		types = append(types, tsType(&refs[i], depth))
+	}
+
+	return strings.Join(types, sep)
+}
+
+func tsLiteral(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return strconv.Quote(value)
+	case nil:
+		return "null"
+	}
+
+	return fmt.Sprint(v)
+}
+
+// tsTypeName turns a schema name into a TypeScript identifier
+func tsTypeName(name string) string {
+	return exported(name)
+}
+
+// tsUnexported turns an operationId or param name into a TypeScript identifier, e.g. X-Request-ID into xRequestID.
+// Reserved words and the names used by the runtime and the generated functions get a trailing underscore.
+func tsUnexported(s string) string {
+	name := exported(s)
+	name = strings.ToLower(name[:1]) + name[1:]
+
+	switch name {
+	case "break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do", "else", "enum",
+		"export", "extends", "false", "finally", "for", "function", "if", "import", "in", "instanceof", "new", "null",
+		"return", "super", "switch", "this", "throw", "true", "try", "typeof", "var", "void", "while", "with",
+		"implements", "interface", "let", "package", "private", "protected", "public", "static", "yield", "await",
+		"arguments", "eval", "body", "params", "options", "request", "configure", "defaults", "fetch":
+		return name + "_"
+	}
+
+	return name
+}
+
+func tsKey(name string) string {
+	if tsIdent.MatchString(name) {
+		return name
+	}
+
+	return strconv.Quote(name)
+}
+
+func tsComment(s string) string {
+	return strings.ReplaceAll(s, "*/", "* /")
+}
+
+func sortedSchemaNames(m map[string]openapi3.SchemaOrRef) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedMediaTypes(m map[string]openapi3.MediaType) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+type Upload struct {
+	Name string   `json:"name" form:"name" required:"true" description:"Shown in */ listings"`
+	Tags []string `json:"tags,omitempty" form:"tags"`
+}
+
+func generateTypeScript(t *testing.T, oas ...*spec.OAS) string {
+	t.Helper()
+
+	ref := &openapi3.Reflector{}
+	ref.Spec = &openapi3.Spec{Openapi: "3.0.3"}
+	ref.Spec.Info.WithTitle("test").WithVersion("1.0.0")
+
+	for _, o := range oas {
+		if err := o.Build(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b strings.Builder
+
+	if err := GenerateTypeScript(&b, ref.Spec); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	ts := generateTypeScript(t,
+		spec.Of("/uploads/:class").Post(Upload{}, Upload{}, http.StatusCreated).AddQueryParam("new", spec.ParamType("integer")),
+		spec.Of("/uploads/:id").Delete(nil).SetOperationID("delete"),
+		spec.Of("/uploads").Get([]Upload{}).SetOperationID("request"),
+		spec.Of("/files").AddConsumes(spec.MIMEMultipart).Put(Upload{}, Upload{}, http.StatusOK),
+	)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"interface", "export interface ClientUpload {\n  /** Shown in * / listings */\n  name: string;\n  tags?: string[];\n}"},
+		{"form schema", "export interface FormClientUpload {"},
+		{"alias", "export type SpecJSONObject = Record<string, unknown> | null;"},
+		{"reserved path param", "export function postUploadsByClass(class_: string, body: ClientUpload, params: { new?: number } = {}, options?: RequestOptions): Promise<ClientUpload>"},
+		{"path expression", "`/uploads/${encodeURIComponent(String(class_))}`"},
+		{"query", `query: { new: params["new"] }`},
+		{"reserved operationId", "export function delete_(id: string, options?: RequestOptions): Promise<void>"},
+		{"no content", `{ response: "none" }`},
+		{"runtime name", "export function request_(options?: RequestOptions): Promise<ClientUpload[]>"},
+		{"multipart", `export function putFiles(body: FormData, options?: RequestOptions): Promise<ClientUpload>`},
+		{"content type", `{ body, contentType: "multipart/form-data" }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(ts, tt.want) {
+				t.Errorf("generated client has no %s:\n%s", tt.want, ts)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -42,6 +44,33 @@ type DatabaseConfig struct {
 	Collections  []string `yaml:"collections"`
 }
 
+// TaskConfig holds the default options of a task type
+type TaskConfig struct {
+	Queue    string         `yaml:"queue"`
+	MaxRetry *int           `yaml:"maxRetry"`
+	Timeout  time.Duration  `yaml:"timeout"`
+	Deadline time.Duration  `yaml:"deadline"`
+	Unique   time.Duration  `yaml:"unique"`
+	Backoff  *BackoffConfig `yaml:"backoff"`
+}
+
+// BackoffConfig holds the retry backoff of a task type, the strategy is fixed, exponential or jittered
+type BackoffConfig struct {
+	Strategy string        `yaml:"strategy"`
+	Delay    time.Duration `yaml:"delay"`
+	Max      time.Duration `yaml:"max"`
+}
+
+// QueueConfig ...
+type QueueConfig struct {
+	Backend       string                 `yaml:"backend"`
+	RedisAddress  string                 `yaml:"redisAddress"`
+	RedisPassword string                 `yaml:"redisPassword"`
+	Concurrency   int                    `yaml:"concurrency"`
+	Queues        map[string]int         `yaml:"queues"`
+	Tasks         map[string]*TaskConfig `yaml:"tasks"`
+}
+
 // Server is generic server config data
 type Server struct {
 	Port int  `yaml:"port" json:"port,omitempty"`
@@ -59,9 +88,13 @@ type Configuration struct {
 	OAuth     *OAuthConfig     `json:"oauth" yaml:"oauth"`
 	Database  *DatabaseConfig  `json:"db" yaml:"db"`
 	Telemetry *TelemetryConfig `json:"telemetry" yaml:"telemetry"`
+	Queue     *QueueConfig     `json:"queue" yaml:"queue"`
 }
 
-var conf *Configuration
+var (
+	mu   sync.RWMutex
+	conf *Configuration
+)
 
 // Load loads the config with viper
 func Load(location, name string) error {
@@ -77,12 +110,18 @@ func Load(location, name string) error {
 			return err
 		}
 
-		err = yaml.Unmarshal(f, &conf)
+		c := &Configuration{}
+
+		err = yaml.Unmarshal(f, c)
 		if err != nil {
 			log.Trace().Err(err).Msg("While unmarshaling config")
 
 			return err
 		}
+
+		mu.Lock()
+		conf = withDefaults(c)
+		mu.Unlock()
 	}
 
 	return nil
@@ -101,11 +140,71 @@ func Autoload() error {
 	return Load(os.Getenv("CONFIG_LOCATION"), os.Getenv("CONFIG_NAME"))
 }
 
-// Get config
+// Get config, an empty configuration is used when none was loaded, e.g. when only exporting the spec
 func Get() *Configuration {
+	mu.RLock()
+	c := conf
+	mu.RUnlock()
+
+	if c != nil {
+		return c
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if conf == nil {
+		conf = withDefaults(&Configuration{})
+	}
+
 	return conf
 }
 
+// withDefaults fills in the sections and values that are not configured, it is applied once when the config is loaded
+func withDefaults(c *Configuration) *Configuration {
+	if c.Server == nil {
+		c.Server = &Server{}
+	}
+
+	if c.OAS == nil {
+		c.OAS = &OASConfig{Title: "API", Version: "0.0.0"}
+	}
+
+	if c.OAuth == nil {
+		c.OAuth = &OAuthConfig{}
+	}
+
+	if c.Database == nil {
+		c.Database = &DatabaseConfig{}
+	}
+
+	if c.Telemetry == nil {
+		c.Telemetry = &TelemetryConfig{}
+	}
+
+	if c.Queue == nil {
+		c.Queue = &QueueConfig{}
+	}
+
+	if c.Queue.Backend == "" {
+		c.Queue.Backend = "redis"
+	}
+
+	if c.Queue.RedisAddress == "" {
+		c.Queue.RedisAddress = "127.0.0.1:6379"
+	}
+
+	if c.Queue.Concurrency == 0 {
+		c.Queue.Concurrency = 11
+	}
+
+	if len(c.Queue.Queues) == 0 {
+		c.Queue.Queues = map[string]int{"critical": 6, "default": 3, "low": 1}
+	}
+
+	return c
+}
+
 func checkDefined(arr []string) {
 	for _, v := range arr {
 		if os.Getenv(v) == "" {
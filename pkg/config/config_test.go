@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("queue:\n  backend: memory\n  concurrency: 2\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Load(dir, "config"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	// Get is called concurrently by request handlers, it must not write the config
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			c := Get()
+
+			if c.Server == nil || c.OAS.Title != "API" {
+				t.Error("missing defaults")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	q := Get().Queue
+
+	if q.Backend != "memory" || q.Concurrency != 2 || q.RedisAddress != "127.0.0.1:6379" || q.Queues["default"] != 3 {
+		t.Errorf("unexpected queue config %+v", q)
+	}
+}
@@ -1,5 +1,32 @@
 package dto
 
+import "errors"
+
 type Sample struct {
 	ID string `json:"id" yaml:"id"`
+}
+
+// EmailDeliveryPayload is the payload of the sample email delivery task
+type EmailDeliveryPayload struct {
+	UserID     int
+	TemplateID string
+}
+
+// Validate is called before the task is enqueued and after it is decoded
+func (p EmailDeliveryPayload) Validate() error {
+	if p.TemplateID == "" {
+		return errors.New("template id is required")
+	}
+
+	return nil
+}
+
+// ImageResizePayload is the request of the sample image resize task
+type ImageResizePayload struct {
+	SourceURL string `json:"sourceUrl" required:"true"`
+}
+
+// ImageResizeResult is the result of the sample image resize task
+type ImageResizeResult struct {
+	URL string `json:"url"`
 }
\ No newline at end of file
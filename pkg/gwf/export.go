@@ -0,0 +1,327 @@
+package gwf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/khvh/gwf/pkg/client"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+const (
+	// ExportEnv is the environment variable holding the file the spec is exported to, when set the
+	// application only builds its routes and writes the spec on Run, without starting the server, queue or telemetry
+	ExportEnv = "GWF_EXPORT_SPEC"
+	// ClientEnv is the environment variable holding the file a Go client is generated to, it enables export mode too
+	ClientEnv = "GWF_EXPORT_CLIENT"
+	// TypeScriptEnv is the environment variable holding the file a TypeScript client is generated to, e.g. ui/src/api/index.ts,
+	// the clients of API versions are written next to it, e.g. ui/src/api/index.v1.ts
+	TypeScriptEnv = "GWF_EXPORT_TS"
+)
+
+// Exporting reports whether the application runs in export mode
+func Exporting() bool {
+	return os.Getenv(ExportEnv) != "" || os.Getenv(ClientEnv) != "" || os.Getenv(TypeScriptEnv) != ""
+}
+
+// GenerateTypeScript writes TypeScript types and a fetch-based client for the spec. When versioning is enabled the
+// client covers the unversioned routes and the routes of the default version, see GenerateVersionTypeScript.
+func (a *App) GenerateTypeScript(w io.Writer) error {
+	if a.versioning != nil && a.versioning.DefaultVersion() != "" {
+		return a.GenerateVersionTypeScript(w, a.versioning.DefaultVersion())
+	}
+
+	return client.GenerateTypeScript(w, a.ref.Spec)
+}
+
+// GenerateVersionTypeScript writes a TypeScript client for the unversioned routes and the routes of an API version
+func (a *App) GenerateVersionTypeScript(w io.Writer, version string) error {
+	if a.versioning == nil {
+		return fmt.Errorf("versioning is not enabled")
+	}
+
+	for _, v := range a.versioning.Versions() {
+		if v.Name() == version {
+			s, err := mergeSpecs(v.Reflector().Spec, a.ref.Spec)
+			if err != nil {
+				return err
+			}
+
+			return client.GenerateTypeScript(w, s)
+		}
+	}
+
+	return fmt.Errorf("unknown API version %s", version)
+}
+
+// writeTypeScript regenerates the TypeScript client at path and the client of every version next to it,
+// e.g. index.v1.ts, leaving the files untouched when nothing changed
+func (a *App) writeTypeScript(path string) error {
+	if err := writeGenerated(path, a.GenerateTypeScript); err != nil {
+		return err
+	}
+
+	if a.versioning == nil {
+		return nil
+	}
+
+	ext := filepath.Ext(path)
+
+	for _, v := range a.versioning.Versions() {
+		version := v.Name()
+		file := strings.TrimSuffix(path, ext) + "." + version + ext
+
+		if err := writeGenerated(file, func(w io.Writer) error { return a.GenerateVersionTypeScript(w, version) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeGenerated writes the output of fn to path unless the file has it already
+func writeGenerated(path string, fn func(w io.Writer) error) error {
+	var b bytes.Buffer
+
+	if err := fn(&b); err != nil {
+		return err
+	}
+
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, b.Bytes()) {
+		return nil
+	}
+
+	return writeFile(path, func(w io.Writer) error {
+		_, err := w.Write(b.Bytes())
+
+		return err
+	})
+}
+
+// mergeSpecs returns a copy of s with the operations, schemas and security schemes of other it does not have.
+// Operations of other with the operationId of an operation of s would give two client functions the same name.
+func mergeSpecs(s, other *openapi3.Spec) (*openapi3.Spec, error) {
+	b, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &openapi3.Spec{}
+
+	if err := merged.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+
+	for _, item := range merged.Paths.MapOfPathItemValues {
+		for _, op := range item.MapOfOperationValues {
+			if op.ID != nil {
+				ids[*op.ID] = true
+			}
+		}
+	}
+
+	for path, item := range other.Paths.MapOfPathItemValues {
+		existing := merged.Paths.MapOfPathItemValues[path]
+
+		for method, op := range item.MapOfOperationValues {
+			if _, ok := existing.MapOfOperationValues[method]; ok {
+				continue
+			}
+
+			if op.ID != nil && ids[*op.ID] {
+				return nil, fmt.Errorf("duplicate operationId %s for %s %s", *op.ID, strings.ToUpper(method), path)
+			}
+
+			existing.WithMapOfOperationValuesItem(method, op)
+		}
+
+		merged.Paths.WithMapOfPathItemValuesItem(path, existing)
+	}
+
+	if other.Components == nil {
+		return merged, nil
+	}
+
+	if other.Components.Schemas != nil {
+		schemas := merged.ComponentsEns().SchemasEns()
+
+		for name, schema := range other.Components.Schemas.MapOfSchemaOrRefValues {
+			if _, ok := schemas.MapOfSchemaOrRefValues[name]; !ok {
+				schemas.WithMapOfSchemaOrRefValuesItem(name, schema)
+			}
+		}
+	}
+
+	if other.Components.SecuritySchemes != nil {
+		schemes := merged.ComponentsEns().SecuritySchemesEns()
+
+		for name, scheme := range other.Components.SecuritySchemes.MapOfSecuritySchemeOrRefValues {
+			if _, ok := schemes.MapOfSecuritySchemeOrRefValues[name]; !ok {
+				schemes.WithMapOfSecuritySchemeOrRefValuesItem(name, scheme)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// GenerateClient writes a Go client package with one typed method per registered operation
+func (a *App) GenerateClient(w io.Writer, pkg string) error {
+	var ops []spec.Operation
+
+	for _, r := range a.routers {
+		ops = append(ops, r.Operations()...)
+	}
+
+	return client.Generate(w, pkg, ops)
+}
+
+// export writes the spec and the clients to the files named by ExportEnv, ClientEnv and TypeScriptEnv
+func (a *App) export() error {
+	if path := os.Getenv(ExportEnv); path != "" {
+		if err := a.exportSpecs(path); err != nil {
+			return err
+		}
+	}
+
+	if path := os.Getenv(ClientEnv); path != "" {
+		pkg := filepath.Base(filepath.Dir(path))
+
+		if err := writeFile(path, func(w io.Writer) error { return a.GenerateClient(w, pkg) }); err != nil {
+			return err
+		}
+	}
+
+	if path := os.Getenv(TypeScriptEnv); path != "" {
+		return a.writeTypeScript(path)
+	}
+
+	return nil
+}
+
+// ExportSpec writes the unversioned spec as json or yaml, servers are left out so the output does not depend on the host
+func (a *App) ExportSpec(w io.Writer, format string) error {
+	return exportSpec(w, a.ref, format)
+}
+
+// ExportVersionSpec writes the spec of an API version as json or yaml
+func (a *App) ExportVersionSpec(w io.Writer, version, format string) error {
+	if a.versioning == nil {
+		return fmt.Errorf("versioning is not enabled")
+	}
+
+	for _, v := range a.versioning.Versions() {
+		if v.Name() == version {
+			return exportSpec(w, v.Reflector(), format)
+		}
+	}
+
+	return fmt.Errorf("unknown API version %s", version)
+}
+
+// exportSpecs writes the unversioned spec to path and every version next to it, e.g. openapi.v1.yaml
+func (a *App) exportSpecs(path string) error {
+	ext := filepath.Ext(path)
+	format := "json"
+
+	if ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+
+	if err := writeFile(path, func(w io.Writer) error { return a.ExportSpec(w, format) }); err != nil {
+		return err
+	}
+
+	if a.versioning == nil {
+		return nil
+	}
+
+	for _, v := range a.versioning.Versions() {
+		version := v.Name()
+		file := strings.TrimSuffix(path, ext) + "." + version + ext
+
+		if err := writeFile(file, func(w io.Writer) error { return a.ExportVersionSpec(w, version, format) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes to a temporary file next to path and renames it on success, a failing fn leaves path untouched
+func writeFile(path string, fn func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if err := fn(f); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	log.Info().Str("file", path).Msg("Exported")
+
+	return nil
+}
+
+func exportSpec(w io.Writer, ref *openapi3.Reflector, format string) error {
+	if err := spec.Validate(ref.Spec); err != nil {
+		return err
+	}
+
+	s := *ref.Spec
+	s.Servers = nil
+
+	var (
+		body []byte
+		err  error
+	)
+
+	switch format {
+	case "json":
+		body, err = s.MarshalJSON()
+	case "yaml", "yml":
+		body, err = s.MarshalYAML()
+	default:
+		return fmt.Errorf("unsupported spec format %s", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+
+	return err
+}
@@ -0,0 +1,112 @@
+package gwf
+
+import (
+	"embed"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/khvh/gwf/pkg/router"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client", "client.go")
+
+	write := func(content string, err error) error {
+		return writeFile(path, func(w io.Writer) error {
+			if _, werr := io.WriteString(w, content); werr != nil {
+				return werr
+			}
+
+			return err
+		})
+	}
+
+	if err := write("package client\n", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := write("partial", errors.New("generating")); err == nil {
+		t.Fatal("expected the generator error")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "package client\n" {
+		t.Errorf("file was overwritten by a failed generation: %q", b)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestGenerateTypeScriptVersions(t *testing.T) {
+	versioning := router.PathVersioning("/api")
+
+	app := Create(embed.FS{}).Versioning(versioning).RegisterRoutes(
+		router.Instance().Register(router.Get[widget]("/widgets/:id", getWidget)),
+		router.Instance().Version(versioning.Version("v1")).Register(router.Post[account, account]("/accounts", createAccount)),
+		router.Instance().Version(versioning.Version("v2")).Register(router.Get[account]("/accounts/:id", getAccount)),
+	)
+
+	dir := t.TempDir()
+
+	if err := app.writeTypeScript(filepath.Join(dir, "index.ts")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		// v1 is the default version
+		{"index.ts", []string{"function getWidget(", "function createAccount("}, []string{"function getAccount("}},
+		{"index.v1.ts", []string{"function getWidget(", "function createAccount("}, []string{"function getAccount("}},
+		{"index.v2.ts", []string{"function getWidget(", "function getAccount("}, []string{"function createAccount("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.want {
+				if !strings.Contains(string(b), s) {
+					t.Errorf("no %s", s)
+				}
+			}
+
+			for _, s := range tt.notWant {
+				if strings.Contains(string(b), s) {
+					t.Errorf("unexpected %s", s)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateTypeScriptDuplicateOperationID(t *testing.T) {
+	versioning := router.PathVersioning("/api")
+
+	app := Create(embed.FS{}).Versioning(versioning).RegisterRoutes(
+		router.Instance().Register(router.Get[widget]("/widgets/:id", getWidget)),
+		router.Instance().Version(versioning.Version("v1")).Register(router.Get[widget]("/gadgets/:id", getWidget)),
+	)
+
+	err := app.GenerateTypeScript(io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "duplicate operationId getWidget") {
+		t.Fatalf("got %v, want a duplicate operationId error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package gwf
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
@@ -9,16 +10,23 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
 	"github.com/khvh/gwf/pkg/queue"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.opentelemetry.io/otel"
 
 	"github.com/khvh/gwf/pkg/config"
+	"github.com/khvh/gwf/pkg/logger"
+	"github.com/khvh/gwf/pkg/outbox"
 	"github.com/khvh/gwf/pkg/router"
+	"github.com/khvh/gwf/pkg/spec"
 	"github.com/khvh/gwf/pkg/telemetry"
 	"github.com/khvh/gwf/pkg/util"
 	"github.com/labstack/echo-contrib/prometheus"
@@ -29,8 +37,16 @@ import (
 
 // App is a structure for handling application things
 type App struct {
-	server *echo.Echo
-	ref    *openapi3.Reflector
+	server     *echo.Echo
+	ref        *openapi3.Reflector
+	versioning *router.Versioning
+	docs       map[string]*document
+	docsMu     sync.Mutex
+	specMW     []echo.MiddlewareFunc
+	routers    []*router.Router
+	tsFile     string
+	validator  *validator
+	queue      *queue.Queue
 }
 
 func getFileSystem(embededFiles embed.FS) http.FileSystem {
@@ -58,7 +74,11 @@ func Create(static embed.FS) *App {
 	server.GET("/docs", echo.WrapHandler(http.StripPrefix("/docs", assetHandler)))
 	server.GET("/docs/*", echo.WrapHandler(http.StripPrefix("/docs", assetHandler)))
 
-	server.Use(middleware.RequestID())
+	server.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(logger.WithRequestID(c.Request().Context(), id)))
+		},
+	}))
 	server.Use(middleware.CORS())
 	server.Use(middleware.Recover())
 
@@ -81,10 +101,19 @@ func Create(static embed.FS) *App {
 		}))
 	}
 
-	return &App{
-		ref:    router.InitReflector(),
+	ref := router.InitReflector()
+
+	app := &App{
+		ref:    ref,
 		server: server,
+		docs: map[string]*document{
+			"": newDocument(ref),
+		},
 	}
+
+	app.mountSpec()
+
+	return app
 }
 
 // Configure adds the ability to configure additional things for Fiber
@@ -95,6 +124,10 @@ func (a *App) Configure(fn func(*echo.Echo)) *App {
 }
 
 func (a *App) EnableTracing() *App {
+	if Exporting() {
+		return a
+	}
+
 	id := strings.ReplaceAll(config.Get().ID, "-", "_")
 
 	telemetry.New()
@@ -105,11 +138,18 @@ func (a *App) EnableTracing() *App {
 }
 
 func (a *App) Frontend(ui embed.FS, dir string) *App {
+	if Exporting() {
+		return a
+	}
+
 	if !config.Get().Server.Dev || !config.Get().Server.UI {
 		return a
 	}
 
 	if config.Get().Server.Dev {
+		a.tsFile = filepath.Join(dir, "src", "api", "index.ts")
+		a.generateTypeScript()
+
 		go a.startYarnDev(dir)
 
 		log.Trace().Msg("Frontend dev server proxy started")
@@ -178,58 +218,162 @@ func (a *App) startYarnDev(dir string) {
 	log.Trace().Err(err).Bytes("out", out).Send()
 }
 
+// Versioning enables API versions, each version gets its own spec under /spec/{version}
+func (a *App) Versioning(versioning *router.Versioning) *App {
+	a.versioning = versioning
+
+	return a
+}
+
 // RegisterRoutes registers router.Router routes
 func (a *App) RegisterRoutes(routes ...*router.Router) *App {
-	for _, r := range routes {
-		r.Build(a.ref, a.server)
+	if err := router.Build(a.ref, a.server, routes...); err != nil {
+		log.Fatal().Err(err).Send()
 	}
 
-	yamlSchema, err := a.ref.Spec.MarshalYAML()
-	if err != nil {
-		log.Fatal().Err(err)
-	}
+	a.routers = append(a.routers, routes...)
 
-	jsonSchema, err := a.ref.Spec.MarshalJSON()
-	if err != nil {
-		log.Fatal().Err(err)
+	for name, ref := range a.reflectors() {
+		if err := spec.Validate(ref.Spec); err != nil {
+			log.Fatal().Err(err).Str("version", name).Send()
+		}
 	}
 
-	a.server.GET("/spec/spec.json", func(c echo.Context) error {
-		c.Response().Header().Set("content-type", "application/openapi+json")
+	a.invalidateSpec()
+	a.generateTypeScript()
 
-		return c.String(http.StatusOK, string(jsonSchema))
-	})
-
-	a.server.GET("/spec/spec.yaml", func(c echo.Context) error {
-		c.Response().Header().Set("content-type", "application/openapi+yaml")
+	return a
+}
 
-		return c.String(http.StatusOK, string(yamlSchema))
-	})
+// generateTypeScript keeps the frontend's TypeScript client in sync with the routes in dev mode
+func (a *App) generateTypeScript() {
+	if a.tsFile == "" {
+		return
+	}
 
-	return a
+	if err := a.writeTypeScript(a.tsFile); err != nil {
+		log.Error().Err(err).Str("file", a.tsFile).Msg("While generating the TypeScript client")
+	}
 }
 
-// Queue creates an Asynq queue and mounts the web interface
+// Queue creates a queue with the backend from the config and mounts the web interface for Redis.
+// The memory backend runs tasks in process, e.g. on laptops without Redis.
 func (a *App) Queue(fn func(q *queue.Queue)) *App {
-	q := queue.
-		CreateServer("127.0.0.1:6379", 11, queue.Queues{
-			"critical": 6,
-			"default":  3,
-			"low":      1,
-		}).
-		MountMonitor("127.0.0.1:6379", "", a.server)
+	if Exporting() {
+		return a
+	}
+
+	conf := config.Get().Queue
+
+	var q *queue.Queue
+
+	switch conf.Backend {
+	case "memory":
+		q = queue.New(queue.NewMemory(queue.WithConcurrency(conf.Concurrency), queue.WithQueues(conf.Queues)))
+	case "redis":
+		q = queue.
+			New(queue.NewRedis(asynq.RedisClientOpt{Addr: conf.RedisAddress, Password: conf.RedisPassword}, conf.Concurrency, conf.Queues)).
+			MountMonitor(conf.RedisAddress, conf.RedisPassword, a.server)
+	default:
+		log.Fatal().Msgf("Unknown queue backend [%s]", conf.Backend)
+	}
+
+	for typename, t := range conf.Tasks {
+		q.Client().Defaults(typename, taskOptions(t)...)
+
+		if t.Backoff != nil {
+			q.Policy(typename, queue.WithBackoff(backoff(typename, t.Backoff)))
+		}
+	}
+
+	a.queue = q
 
 	fn(q)
 
 	q.Run()
 
-	log.Trace().Msgf("Asynq running on http://0.0.0.0:%d/monitoring/tasks", config.Get().Server.Port)
+	if conf.Backend == "redis" {
+		log.Trace().Msgf("Asynq running on http://0.0.0.0:%d/monitoring/tasks", config.Get().Server.Port)
+	}
+
+	return a
+}
+
+// Outbox creates the outbox table in db and relays the tasks written with outbox.Tx to the queue, call it after Queue.
+// The relay stops when the queue shuts down.
+func (a *App) Outbox(db *sqlx.DB, opts ...outbox.Option) *App {
+	if Exporting() {
+		return a
+	}
+
+	if a.queue == nil {
+		log.Fatal().Msg("The queue is not created, call Queue before Outbox")
+	}
+
+	o := outbox.New(db, a.queue.Client(), opts...)
+
+	if err := o.CreateTable(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("While creating the outbox table")
+	}
+
+	go o.Run(a.queue.Context())
 
 	return a
 }
 
-// Run runs the application
+// taskOptions converts the configured defaults of a task type to options
+func taskOptions(t *config.TaskConfig) []asynq.Option {
+	var opts []asynq.Option
+
+	if t.Queue != "" {
+		opts = append(opts, asynq.Queue(t.Queue))
+	}
+
+	if t.MaxRetry != nil {
+		opts = append(opts, asynq.MaxRetry(*t.MaxRetry))
+	}
+
+	if t.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(t.Timeout))
+	}
+
+	if t.Deadline > 0 {
+		opts = append(opts, queue.DeadlineIn(t.Deadline))
+	}
+
+	if t.Unique > 0 {
+		opts = append(opts, asynq.Unique(t.Unique))
+	}
+
+	return opts
+}
+
+// backoff converts the configured retry backoff of a task type
+func backoff(typename string, b *config.BackoffConfig) asynq.RetryDelayFunc {
+	switch b.Strategy {
+	case "fixed":
+		return queue.Fixed(b.Delay)
+	case "exponential":
+		return queue.Exponential(b.Delay, b.Max)
+	case "jittered":
+		return queue.Jittered(b.Delay, b.Max)
+	}
+
+	log.Fatal().Msgf("Unknown backoff strategy [%s] for task [%s]", b.Strategy, typename)
+
+	return nil
+}
+
+// Run runs the application, in export mode it writes the spec and returns instead
 func (a *App) Run() {
+	if Exporting() {
+		if err := a.export(); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+
+		return
+	}
+
 	id := config.Get().ID
 	port := config.Get().Server.Port
 
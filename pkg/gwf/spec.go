@@ -0,0 +1,242 @@
+package gwf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// document is a marshaled OpenAPI document, rebuilt from its reflector after routes are added
+type document struct {
+	ref   *openapi3.Reflector
+	mu    sync.Mutex
+	dirty bool
+	json  body
+	yaml  body
+}
+
+// body is a marshaled document with its entity tag
+type body struct {
+	data []byte
+	etag string
+}
+
+// newBody tags data with a weak ETag, the spec endpoints compress responses so the bytes sent differ per encoding
+func newBody(data []byte) body {
+	sum := sha256.Sum256(data)
+
+	return body{
+		data: data,
+		etag: `W/"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+}
+
+// specVersion describes a version for the docs version picker
+type specVersion struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Default    bool   `json:"default"`
+	Deprecated bool   `json:"deprecated"`
+	Sunset     string `json:"sunset,omitempty"`
+}
+
+func newDocument(ref *openapi3.Reflector) *document {
+	return &document{
+		ref:   ref,
+		dirty: true,
+	}
+}
+
+func (d *document) invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dirty = true
+}
+
+// get returns the document in the given format and its ETag, marshaling it again if routes were added since the last call
+func (d *document) get(format string) (body, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dirty {
+		json, err := d.ref.Spec.MarshalJSON()
+		if err != nil {
+			return body{}, err
+		}
+
+		yaml, err := d.ref.Spec.MarshalYAML()
+		if err != nil {
+			return body{}, err
+		}
+
+		d.json, d.yaml, d.dirty = newBody(json), newBody(yaml), false
+	}
+
+	if format == "yaml" {
+		return d.yaml, nil
+	}
+
+	return d.json, nil
+}
+
+// mountSpec registers the spec endpoints, they always serve the current state of the reflectors
+func (a *App) mountSpec() {
+	group := a.server.Group("/spec", middleware.Gzip(), a.specAuth)
+
+	group.GET("/spec.json", a.specHandler("", "json"))
+	group.GET("/spec.yaml", a.specHandler("", "yaml"))
+	group.GET("/versions.json", a.specVersions)
+	group.GET("/:version/spec.json", a.specHandler(":version", "json"))
+	group.GET("/:version/spec.yaml", a.specHandler(":version", "yaml"))
+}
+
+// ProtectSpec adds middleware, e.g. authentication, to the spec endpoints
+func (a *App) ProtectSpec(mw ...echo.MiddlewareFunc) *App {
+	a.specMW = append(a.specMW, mw...)
+
+	return a
+}
+
+func (a *App) specAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		handler := next
+
+		for i := len(a.specMW) - 1; i >= 0; i-- {
+			handler = a.specMW[i](handler)
+		}
+
+		return handler(c)
+	}
+}
+
+// document returns the document of an API version, "" is the unversioned document
+func (a *App) document(version string) *document {
+	a.docsMu.Lock()
+	defer a.docsMu.Unlock()
+
+	if doc, ok := a.docs[version]; ok {
+		return doc
+	}
+
+	if a.versioning == nil {
+		return nil
+	}
+
+	for _, v := range a.versioning.Versions() {
+		if v.Name() == version {
+			a.docs[version] = newDocument(v.Reflector())
+
+			return a.docs[version]
+		}
+	}
+
+	return nil
+}
+
+// invalidateSpec marks all documents for rebuilding
+func (a *App) invalidateSpec() {
+	a.docsMu.Lock()
+	defer a.docsMu.Unlock()
+
+	for _, doc := range a.docs {
+		doc.invalidate()
+	}
+
+	if a.validator != nil {
+		a.validator.invalidate()
+	}
+}
+
+func (a *App) specHandler(version, format string) echo.HandlerFunc {
+	contentType := "application/openapi+json"
+
+	if format == "yaml" {
+		contentType = "application/openapi+yaml"
+	}
+
+	return func(c echo.Context) error {
+		name := version
+		if name == ":version" {
+			name = c.Param("version")
+		}
+
+		doc := a.document(name)
+		if doc == nil {
+			return echo.ErrNotFound
+		}
+
+		b, err := doc.get(format)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("ETag", b.etag)
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+
+		if matchETag(c.Request().Header.Get("If-None-Match"), b.etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		return c.Blob(http.StatusOK, contentType, b.data)
+	}
+}
+
+func (a *App) specVersions(c echo.Context) error {
+	versions := []specVersion{}
+
+	if a.versioning == nil {
+		return c.JSON(http.StatusOK, versions)
+	}
+
+	for _, v := range a.versioning.Versions() {
+		version := specVersion{
+			Name:       v.Name(),
+			URL:        "/spec/" + v.Name() + "/spec.json",
+			Default:    v.Name() == a.versioning.DefaultVersion(),
+			Deprecated: v.Deprecated(),
+		}
+
+		if !v.Sunset().IsZero() {
+			version.Sunset = v.Sunset().UTC().Format(http.TimeFormat)
+		}
+
+		versions = append(versions, version)
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+// matchETag compares the ETags of an If-None-Match header with etag using the weak comparison
+func matchETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reflectors returns the reflectors of all documents keyed by version, "" is the unversioned one
+func (a *App) reflectors() map[string]*openapi3.Reflector {
+	refs := map[string]*openapi3.Reflector{"": a.ref}
+
+	if a.versioning != nil {
+		for _, v := range a.versioning.Versions() {
+			refs[v.Name()] = v.Reflector()
+		}
+	}
+
+	return refs
+}
@@ -0,0 +1,86 @@
+package gwf
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/khvh/gwf/pkg/router"
+)
+
+type widget struct {
+	Name string `json:"name"`
+}
+
+func getWidget(c echo.Context) error {
+	return c.JSON(http.StatusOK, widget{})
+}
+
+func createWidget(c echo.Context) error {
+	return c.JSON(http.StatusOK, widget{})
+}
+
+func TestSpecETag(t *testing.T) {
+	app := Create(embed.FS{}).RegisterRoutes(router.Instance().Register(router.Get[widget]("/widgets/:id", getWidget)))
+
+	get := func(ifNoneMatch, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/spec/spec.json", nil)
+
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		if encoding != "" {
+			req.Header.Set(echo.HeaderAcceptEncoding, encoding)
+		}
+
+		rec := httptest.NewRecorder()
+		app.server.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	etag := get("", "").Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("got ETag %q, want a weak ETag", etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		encoding    string
+		code        int
+	}{
+		{"no validator", "", "", http.StatusOK},
+		{"matching", etag, "", http.StatusNotModified},
+		{"matching compressed", etag, "gzip", http.StatusNotModified},
+		{"matching strong", strings.TrimPrefix(etag, "W/"), "", http.StatusNotModified},
+		{"one of many", `"other", ` + etag, "", http.StatusNotModified},
+		{"any", "*", "", http.StatusNotModified},
+		{"stale", `W/"other"`, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.ifNoneMatch, tt.encoding)
+
+			if rec.Code != tt.code {
+				t.Errorf("got %d, want %d", rec.Code, tt.code)
+			}
+
+			if got := rec.Header().Get("ETag"); got != etag {
+				t.Errorf("got ETag %q, want %q", got, etag)
+			}
+		})
+	}
+
+	app.RegisterRoutes(router.Instance().Register(router.Post[widget, widget]("/widgets", createWidget)))
+
+	if rec := get(etag, ""); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("got %d with ETag %q after adding routes, want %d with a new ETag", rec.Code, rec.Header().Get("ETag"), http.StatusOK)
+	}
+}
@@ -0,0 +1,236 @@
+package gwf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+
+	"github.com/khvh/gwf/pkg/config"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+var validationCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "spec_validation_failures_total",
+		Help: "The total number of requests and responses that did not match the OpenAPI spec",
+	},
+	[]string{"kind", "method", "path"},
+)
+
+// ValidationOption configures request and response validation
+type ValidationOption func(v *validator)
+
+// ValidateResponses enables or disables validating responses, enabled in dev mode by default.
+// Mismatching responses are logged and counted, never changed.
+func ValidateResponses(enabled bool) ValidationOption {
+	return func(v *validator) {
+		v.responses = enabled
+	}
+}
+
+// SkipRequests disables validating requests, e.g. to only check responses in dev mode
+func SkipRequests() ValidationOption {
+	return func(v *validator) {
+		v.requests = false
+	}
+}
+
+// Validate validates requests against the operations of the spec, invalid requests get a 400 with a spec.Error.
+// Requests to routes missing from the spec, e.g. /docs or /metrics, are not validated.
+func (a *App) Validate(opts ...ValidationOption) *App {
+	v := &validator{
+		app:       a,
+		requests:  true,
+		responses: config.Get().Server.Dev,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	a.validator = v
+	a.server.Use(v.middleware)
+
+	return a
+}
+
+// validator holds kin-openapi routers for every document, rebuilt after routes are added
+type validator struct {
+	app       *App
+	requests  bool
+	responses bool
+	mu        sync.Mutex
+	routers   map[string]routers.Router
+}
+
+func (v *validator) invalidate() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.routers = nil
+}
+
+func (v *validator) load() (map[string]routers.Router, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.routers != nil {
+		return v.routers, nil
+	}
+
+	res := map[string]routers.Router{}
+
+	for name, ref := range v.app.reflectors() {
+		doc, err := spec.Load(ref.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		// Servers list the addresses of the host, requests are matched by path only
+		doc.Servers = nil
+
+		router, err := legacy.NewRouter(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		res[name] = router
+	}
+
+	v.routers = res
+
+	return res, nil
+}
+
+// find returns the operation of the request in the document of the version it selects, then in the unversioned one.
+// Other versions are not tried, a request for an operation its version lacks is left to the router.
+func (v *validator) find(req *http.Request) (*routers.Route, map[string]string, error) {
+	all, err := v.load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := []string{""}
+
+	if v.app.versioning != nil {
+		names = []string{v.app.versioning.Resolve(req), ""}
+	}
+
+	for _, name := range names {
+		router, ok := all[name]
+		if !ok {
+			continue
+		}
+
+		if route, params, err := router.FindRoute(req); err == nil {
+			return route, params, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+func (v *validator) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		route, params, err := v.find(c.Request())
+		if err != nil {
+			log.Error().Err(err).Msg("While loading the spec for validation")
+
+			return next(c)
+		}
+
+		if route == nil {
+			return next(c)
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request(),
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				MultiError:         true,
+			},
+		}
+
+		if v.requests {
+			if err := openapi3filter.ValidateRequest(c.Request().Context(), input); err != nil {
+				validationCounter.WithLabelValues("request", route.Method, route.Path).Inc()
+
+				return c.JSON(http.StatusBadRequest, &spec.Error{Code: "invalid_request", Msg: validationMessage(err)})
+			}
+		}
+
+		if !v.responses {
+			return next(c)
+		}
+
+		rec := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = rec
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		res := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 c.Response().Status,
+			Header:                 c.Response().Header(),
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+				MultiError:            true,
+			},
+		}
+
+		if err := openapi3filter.ValidateResponse(c.Request().Context(), res); err != nil {
+			validationCounter.WithLabelValues("response", route.Method, route.Path).Inc()
+
+			log.Warn().
+				Err(err).
+				Str("method", route.Method).
+				Str("path", route.Path).
+				Int("code", c.Response().Status).
+				Msg("Response does not match the spec")
+		}
+
+		return nil
+	}
+}
+
+// validationMessage shortens kin-openapi errors to what a client needs to fix the request
+func validationMessage(err error) string {
+	msg := err.Error()
+
+	var requestErr *openapi3filter.RequestError
+
+	if errors.As(err, &requestErr) {
+		msg = requestErr.Error()
+	}
+
+	// Schema errors append the schema and the value, which only repeat the request
+	return strings.TrimSpace(strings.Split(msg, "\nSchema:")[0])
+}
+
+// responseRecorder keeps a copy of the response body for validation
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
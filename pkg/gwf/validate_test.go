@@ -0,0 +1,154 @@
+package gwf
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/khvh/gwf/pkg/config"
+	"github.com/khvh/gwf/pkg/router"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+type account struct {
+	Name string `json:"name" required:"true" minLength:"1"`
+}
+
+func createAccount(c echo.Context) error {
+	return c.JSON(http.StatusOK, account{Name: "created"})
+}
+
+func getAccount(c echo.Context) error {
+	// The spec requires a name
+	return c.JSON(http.StatusOK, map[string]string{})
+}
+
+func newValidatedApp(t *testing.T, versioning *router.Versioning, routers ...*router.Router) *App {
+	t.Helper()
+
+	a := Create(embed.FS{})
+
+	if versioning != nil {
+		a.Versioning(versioning)
+	}
+
+	a.Configure(func(e *echo.Echo) {
+		e.POST("/health", func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		})
+	})
+
+	return a.RegisterRoutes(routers...).Validate()
+}
+
+func TestValidateRequests(t *testing.T) {
+	versioning := router.HeaderVersioning("X-API-Version")
+
+	versioned := newValidatedApp(t, versioning,
+		router.Instance().Version(versioning.Version("v1")).Register(router.Post[account, account]("/accounts", createAccount)),
+		router.Instance().Version(versioning.Version("v2")).Register(router.Get[account]("/accounts/:id", getAccount)),
+	)
+
+	app := newValidatedApp(t, nil, router.Instance().Register(
+		router.Post[account, account]("/accounts", createAccount).Query("limit", spec.ParamType("integer")),
+	))
+
+	tests := []struct {
+		name    string
+		app     *App
+		path    string
+		version string
+		body    string
+		code    int
+		error   string
+	}{
+		{name: "valid", app: app, path: "/accounts?limit=1", body: `{"name":"a"}`, code: http.StatusOK},
+		{name: "invalid body", app: app, path: "/accounts", body: `{"name":""}`, code: http.StatusBadRequest, error: "invalid_request"},
+		{name: "missing body field", app: app, path: "/accounts", body: `{}`, code: http.StatusBadRequest, error: "invalid_request"},
+		{name: "invalid param", app: app, path: "/accounts?limit=many", body: `{"name":"a"}`, code: http.StatusBadRequest, error: "invalid_request"},
+		{name: "route missing from the spec", app: app, path: "/health", body: `{}`, code: http.StatusOK},
+		{name: "version of the request", app: versioned, path: "/accounts", version: "v1", body: `{}`, code: http.StatusBadRequest, error: "invalid_request"},
+		// v2 has no POST /accounts, the request is not validated against v1 and the router rejects the version
+		{name: "operation missing from the version", app: versioned, path: "/accounts", version: "v2", body: `{}`, code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			if tt.version != "" {
+				req.Header.Set("X-API-Version", tt.version)
+			}
+
+			rec := httptest.NewRecorder()
+			tt.app.server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+
+			var res spec.Error
+
+			_ = json.Unmarshal(rec.Body.Bytes(), &res)
+
+			if res.Code != tt.error {
+				t.Errorf("got error %q, want %q: %s", res.Code, tt.error, rec.Body)
+			}
+		})
+	}
+}
+
+func TestValidateResponsesInDevMode(t *testing.T) {
+	conf := config.Get()
+	dev := conf.Server.Dev
+	conf.Server.Dev = true
+
+	defer func() { conf.Server.Dev = dev }()
+
+	app := newValidatedApp(t, nil, router.Instance().Register(
+		router.Get[account]("/accounts/:id", getAccount),
+		router.Post[account, account]("/accounts", createAccount),
+	))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		code     int
+		mismatch float64
+	}{
+		{"matching response", http.MethodPost, "/accounts", `{"name":"a"}`, http.StatusOK, 0},
+		{"mismatching response", http.MethodGet, "/accounts/1", "", http.StatusOK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := strings.Replace(tt.path, "/1", "/{id}", 1)
+			counter := validationCounter.WithLabelValues("response", tt.method, route)
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			rec := httptest.NewRecorder()
+			app.server.ServeHTTP(rec, req)
+
+			// Mismatching responses are sent unchanged
+			if rec.Code != tt.code {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+
+			if got := testutil.ToFloat64(counter) - before; got != tt.mismatch {
+				t.Errorf("counted %v mismatches, want %v", got, tt.mismatch)
+			}
+		})
+	}
+}
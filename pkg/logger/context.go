@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID and a logger that logs it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	l := Ctx(ctx).With().Str("request_id", id).Logger()
+
+	return l.WithContext(ctx)
+}
+
+// RequestID returns the request ID of the context, "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// Ctx returns the logger of the context, the global logger if there is none
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l != zerolog.DefaultContextLogger && l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+
+	return &log.Logger
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// options are the asynq options stored with a task, delays and deadlines are stored as absolute times
+type options struct {
+	Queue     string        `json:"queue,omitempty"`
+	MaxRetry  *int          `json:"max_retry,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	Deadline  *time.Time    `json:"deadline,omitempty"`
+	Unique    time.Duration `json:"unique,omitempty"`
+	ProcessAt *time.Time    `json:"process_at,omitempty"`
+	Retention time.Duration `json:"retention,omitempty"`
+	Group     string        `json:"group,omitempty"`
+}
+
+func encodeOptions(opts []asynq.Option) (string, error) {
+	var o options
+
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.MaxRetryOpt:
+			n := opt.Value().(int)
+			o.MaxRetry = &n
+		case asynq.QueueOpt:
+			o.Queue = opt.Value().(string)
+		case asynq.TimeoutOpt:
+			o.Timeout = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			t := opt.Value().(time.Time)
+			o.Deadline = &t
+		case asynq.UniqueOpt:
+			o.Unique = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			t := opt.Value().(time.Time)
+			o.ProcessAt = &t
+		case asynq.ProcessInOpt:
+			t := time.Now().Add(opt.Value().(time.Duration))
+			o.ProcessAt = &t
+		case asynq.RetentionOpt:
+			o.Retention = opt.Value().(time.Duration)
+		case asynq.GroupOpt:
+			o.Group = opt.Value().(string)
+		}
+	}
+
+	b, err := json.Marshal(o)
+
+	return string(b), err
+}
+
+func decodeOptions(s string) ([]asynq.Option, error) {
+	var (
+		o    options
+		opts []asynq.Option
+	)
+
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		return nil, err
+	}
+
+	if o.Queue != "" {
+		opts = append(opts, asynq.Queue(o.Queue))
+	}
+
+	if o.MaxRetry != nil {
+		opts = append(opts, asynq.MaxRetry(*o.MaxRetry))
+	}
+
+	if o.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(o.Timeout))
+	}
+
+	if o.Deadline != nil {
+		opts = append(opts, asynq.Deadline(*o.Deadline))
+	}
+
+	if o.Unique > 0 {
+		opts = append(opts, asynq.Unique(o.Unique))
+	}
+
+	if o.ProcessAt != nil {
+		opts = append(opts, asynq.ProcessAt(*o.ProcessAt))
+	}
+
+	if o.Retention > 0 {
+		opts = append(opts, asynq.Retention(o.Retention))
+	}
+
+	if o.Group != "" {
+		opts = append(opts, asynq.Group(o.Group))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,367 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+
+	"github.com/khvh/gwf/pkg/queue"
+)
+
+var (
+	publishedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "The total number of outbox tasks published to the queue",
+		},
+		[]string{"task_type"},
+	)
+
+	failedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_failures_total",
+			Help: "The total number of times publishing an outbox task failed",
+		},
+		[]string{"task_type"},
+	)
+
+	pendingGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_pending_tasks",
+			Help: "The number of outbox tasks waiting to be published",
+		},
+	)
+
+	failedGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_failed_tasks",
+			Help: "The number of outbox tasks that are no longer published as they failed too many times",
+		},
+	)
+
+	lagHistogram = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "outbox_publish_lag_seconds",
+			Help:    "The time between writing a task to the outbox and publishing it",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{"task_type"},
+	)
+)
+
+// Option configures an Outbox
+type Option func(o *Outbox)
+
+// WithTable sets the outbox table, gwf_outbox by default
+func WithTable(name string) Option {
+	return func(o *Outbox) {
+		o.table = name
+	}
+}
+
+// WithInterval sets how often the relay looks for tasks, every second by default
+func WithInterval(d time.Duration) Option {
+	return func(o *Outbox) {
+		o.interval = d
+	}
+}
+
+// WithBatchSize sets how many tasks the relay publishes at once, 100 by default
+func WithBatchSize(n int) Option {
+	return func(o *Outbox) {
+		o.batch = n
+	}
+}
+
+// WithMaxAttempts sets how many times the relay tries to publish a task, 25 by default, 0 tries forever.
+// A task that keeps failing is marked as failed and stays in the table with its last error, setting its failed_at
+// back to NULL publishes it again.
+func WithMaxAttempts(n int) Option {
+	return func(o *Outbox) {
+		o.maxAttempts = n
+	}
+}
+
+// Outbox writes tasks to a table in the caller's transaction, the relay publishes them to the queue after commit.
+// Tasks are published at least once, the ID of a row is the task ID in the queue so republishing is rejected as
+// long as the queue keeps the task, set asynq.Retention to cover handlers that must not run twice.
+type Outbox struct {
+	db          *sqlx.DB
+	client      *queue.Client
+	table       string
+	interval    time.Duration
+	batch       int
+	maxAttempts int
+}
+
+var outboxInstance *Outbox
+
+// New creates an outbox publishing with client, it becomes the outbox of the package level Tx
+func New(db *sqlx.DB, client *queue.Client, opts ...Option) *Outbox {
+	o := &Outbox{
+		db:          db,
+		client:      client,
+		table:       "gwf_outbox",
+		interval:    time.Second,
+		batch:       100,
+		maxAttempts: 25,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	outboxInstance = o
+
+	return o
+}
+
+// CreateTable creates the outbox table unless it exists, it supports PostgreSQL and SQLite
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	payload, timestamp := "BLOB", "TIMESTAMP"
+
+	if o.postgres() {
+		payload, timestamp = "BYTEA", "TIMESTAMPTZ"
+	}
+
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id         VARCHAR(255) PRIMARY KEY,
+	type       VARCHAR(255) NOT NULL,
+	payload    %s,
+	options    TEXT NOT NULL,
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	failed_at  %[3]s,
+	created_at %[3]s NOT NULL
+)`, o.table, payload, timestamp))
+
+	return err
+}
+
+// Tx returns an Enqueuer writing to the outbox in tx, e.g. for queue.Task.EnqueueTo
+func (o *Outbox) Tx(tx *sqlx.Tx) queue.Enqueuer {
+	return &writer{
+		outbox: o,
+		tx:     tx,
+	}
+}
+
+// Tx returns an Enqueuer writing to the outbox created last with New
+func Tx(tx *sqlx.Tx) queue.Enqueuer {
+	if outboxInstance == nil {
+		log.Panic().Msg("outbox is not created, call outbox.New first")
+	}
+
+	return outboxInstance.Tx(tx)
+}
+
+// Enqueue writes a task to the outbox in tx, asynq.TaskID sets the deduplication key, a random one is used otherwise.
+// A task with a key that is still in the outbox returns asynq.ErrTaskIDConflict without failing the transaction.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sqlx.Tx, task *asynq.Task, opts ...asynq.Option) (*queue.TaskInfo, error) {
+	id := uuid.NewString()
+
+	for _, opt := range opts {
+		if opt.Type() == asynq.TaskIDOpt {
+			id = opt.Value().(string)
+		}
+	}
+
+	encoded, err := encodeOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(
+		`INSERT INTO %s (id, type, payload, options, created_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		o.table,
+	)), id, task.Type(), task.Payload(), encoded, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("outbox %s: %w", id, asynq.ErrTaskIDConflict)
+	}
+
+	return &queue.TaskInfo{
+		ID:      id,
+		Type:    task.Type(),
+		Payload: task.Payload(),
+		State:   asynq.TaskStatePending,
+	}, nil
+}
+
+// Run relays tasks until ctx is done
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := o.Relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("While relaying the outbox")
+		}
+
+		// A full batch means more tasks are waiting
+		if err == nil && n == o.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type row struct {
+	ID        string    `db:"id"`
+	Type      string    `db:"type"`
+	Payload   []byte    `db:"payload"`
+	Options   string    `db:"options"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Relay publishes a batch of tasks and deletes them from the outbox, it returns the number of tasks published.
+// Tasks that fail are retried on the next call after the tasks that did not fail yet, until they reach the maximum
+// number of attempts and are marked as failed.
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	query := fmt.Sprintf(
+		`SELECT id, type, payload, options, attempts, created_at FROM %s WHERE failed_at IS NULL ORDER BY attempts, created_at, id LIMIT ?`,
+		o.table,
+	)
+
+	var (
+		tx   *sqlx.Tx
+		rows []row
+		err  error
+	)
+
+	// PostgreSQL locks the rows of the batch until they are deleted, so instances relay concurrently without publishing
+	// the same rows. SQLite would lock the whole database while publishing, the rows are selected outside of a
+	// transaction instead, a row published twice is rejected by the queue as its ID is the task ID.
+	if o.postgres() {
+		if tx, err = o.db.BeginTxx(ctx, nil); err != nil {
+			return 0, err
+		}
+
+		defer tx.Rollback()
+
+		err = tx.SelectContext(ctx, &rows, tx.Rebind(query+` FOR UPDATE SKIP LOCKED`), o.batch)
+	} else {
+		err = o.db.SelectContext(ctx, &rows, o.db.Rebind(query), o.batch)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	errs := make([]error, len(rows))
+
+	for i, r := range rows {
+		opts, err := decodeOptions(r.Options)
+		if err == nil {
+			_, err = o.client.Enqueue(ctx, asynq.NewTask(r.Type, r.Payload), append(opts, asynq.TaskID(r.ID))...)
+		}
+
+		// The task is in the queue already when an earlier relay could not delete it
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) && !errors.Is(err, asynq.ErrDuplicateTask) {
+			errs[i] = err
+		}
+	}
+
+	if tx == nil {
+		if tx, err = o.db.BeginTxx(ctx, nil); err != nil {
+			return 0, err
+		}
+
+		defer tx.Rollback()
+	}
+
+	published := 0
+
+	for i, r := range rows {
+		if errs[i] != nil {
+			failedCounter.WithLabelValues(r.Type).Inc()
+
+			var failedAt *time.Time
+
+			if o.maxAttempts > 0 && r.Attempts+1 >= o.maxAttempts {
+				now := time.Now().UTC()
+				failedAt = &now
+
+				log.Error().Err(errs[i]).Str("id", r.ID).Str("type", r.Type).Msg("Giving up publishing an outbox task")
+			}
+
+			if _, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(
+				`UPDATE %s SET attempts = attempts + 1, last_error = ?, failed_at = ? WHERE id = ?`, o.table,
+			)), errs[i].Error(), failedAt, r.ID); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, o.table)), r.ID); err != nil {
+			return 0, err
+		}
+
+		published++
+	}
+
+	var counts struct {
+		Pending int `db:"pending"`
+		Failed  int `db:"failed"`
+	}
+
+	if err := tx.GetContext(ctx, &counts, fmt.Sprintf(
+		`SELECT COUNT(*) - COUNT(failed_at) AS pending, COUNT(failed_at) AS failed FROM %s`, o.table,
+	)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	pendingGauge.Set(float64(counts.Pending))
+	failedGauge.Set(float64(counts.Failed))
+
+	for i, r := range rows {
+		if errs[i] == nil {
+			publishedCounter.WithLabelValues(r.Type).Inc()
+			lagHistogram.WithLabelValues(r.Type).Observe(time.Since(r.CreatedAt).Seconds())
+		}
+	}
+
+	return published, nil
+}
+
+func (o *Outbox) postgres() bool {
+	switch o.db.DriverName() {
+	case "postgres", "pgx":
+		return true
+	}
+
+	return false
+}
+
+// writer binds an outbox to a transaction
+type writer struct {
+	outbox *Outbox
+	tx     *sqlx.Tx
+}
+
+func (w *writer) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*queue.TaskInfo, error) {
+	return w.outbox.Enqueue(ctx, w.tx, task, opts...)
+}
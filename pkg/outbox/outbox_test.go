@@ -0,0 +1,490 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/khvh/gwf/pkg/queue"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// failing is a Memory backend rejecting the tasks of some types
+type failing struct {
+	*queue.Memory
+	types map[string]bool
+}
+
+func (f *failing) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*queue.TaskInfo, error) {
+	if f.types[task.Type()] {
+		return nil, errUnavailable
+	}
+
+	return f.Memory.Enqueue(ctx, task, opts...)
+}
+
+func newOutbox(t *testing.T, fail []string, opts ...Option) (*Outbox, *queue.Memory) {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	backend := &failing{
+		Memory: queue.NewMemory(queue.Synchronous()),
+		types:  map[string]bool{},
+	}
+
+	for _, typename := range fail {
+		backend.types[typename] = true
+	}
+
+	o := New(db, queue.New(backend).Client(), opts...)
+
+	if err := o.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return o, backend.Memory
+}
+
+// write enqueues the tasks in a transaction, committed unless rollback is set
+func write(t *testing.T, o *Outbox, rollback bool, tasks ...*asynq.Task) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tx, err := o.db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range tasks {
+		if _, err := o.Tx(tx).Enqueue(ctx, task, asynq.TaskID(task.Type())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rollback {
+		err = tx.Rollback()
+	} else {
+		err = tx.Commit()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pending(t *testing.T, o *Outbox) []string {
+	t.Helper()
+
+	var ids []string
+
+	if err := o.db.Select(&ids, `SELECT id FROM gwf_outbox ORDER BY id`); err != nil {
+		t.Fatal(err)
+	}
+
+	return ids
+}
+
+func queued(m *queue.Memory) []string {
+	var ids []string
+
+	for _, info := range m.Tasks() {
+		ids = append(ids, info.ID)
+	}
+
+	return ids
+}
+
+func TestRelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		tasks    []string
+		rollback bool
+		fail     []string
+		batch    int
+		// relays is the number of times Relay is called
+		relays    int
+		published int
+		queued    []string
+		pending   []string
+	}{
+		{
+			name:      "published after commit",
+			tasks:     []string{"a", "b"},
+			relays:    1,
+			published: 2,
+			queued:    []string{"a", "b"},
+		},
+		{
+			name:     "not published after rollback",
+			tasks:    []string{"a", "b"},
+			rollback: true,
+			relays:   1,
+		},
+		{
+			name:      "kept when publishing fails",
+			tasks:     []string{"a", "b"},
+			fail:      []string{"a"},
+			relays:    1,
+			published: 1,
+			queued:    []string{"b"},
+			pending:   []string{"a"},
+		},
+		{
+			name:      "batched",
+			tasks:     []string{"a", "b", "c"},
+			batch:     2,
+			relays:    1,
+			published: 2,
+			queued:    []string{"a", "b"},
+			pending:   []string{"c"},
+		},
+		{
+			name:      "every batch",
+			tasks:     []string{"a", "b", "c"},
+			batch:     2,
+			relays:    2,
+			published: 1,
+			queued:    []string{"a", "b", "c"},
+		},
+		{
+			name:      "failed tasks after the others",
+			tasks:     []string{"a", "b", "c"},
+			fail:      []string{"a"},
+			batch:     2,
+			relays:    2,
+			published: 1,
+			queued:    []string{"b", "c"},
+			pending:   []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+
+			if tt.batch > 0 {
+				opts = append(opts, WithBatchSize(tt.batch))
+			}
+
+			o, m := newOutbox(t, tt.fail, opts...)
+
+			var tasks []*asynq.Task
+
+			for _, typename := range tt.tasks {
+				tasks = append(tasks, asynq.NewTask(typename, []byte(`{}`)))
+			}
+
+			write(t, o, tt.rollback, tasks...)
+
+			var (
+				n   int
+				err error
+			)
+
+			for i := 0; i < tt.relays; i++ {
+				if n, err = o.Relay(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n != tt.published {
+				t.Errorf("last relay published %d tasks, want %d", n, tt.published)
+			}
+
+			if got := queued(m); !equal(got, tt.queued) {
+				t.Errorf("queued %v, want %v", got, tt.queued)
+			}
+
+			if got := pending(t, o); !equal(got, tt.pending) {
+				t.Errorf("pending %v, want %v", got, tt.pending)
+			}
+		})
+	}
+}
+
+func TestRelayFailure(t *testing.T) {
+	o, _ := newOutbox(t, []string{"a"})
+
+	write(t, o, false, asynq.NewTask("a", nil))
+
+	for i := 0; i < 2; i++ {
+		if _, err := o.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var r struct {
+		Attempts  int    `db:"attempts"`
+		LastError string `db:"last_error"`
+	}
+
+	if err := o.db.Get(&r, `SELECT attempts, last_error FROM gwf_outbox WHERE id = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Attempts != 2 || !strings.Contains(r.LastError, errUnavailable.Error()) {
+		t.Errorf("attempts %d and last error %q, want 2 and an error containing %q", r.Attempts, r.LastError, errUnavailable)
+	}
+}
+
+func TestRelayMaxAttempts(t *testing.T) {
+	o, m := newOutbox(t, []string{"a"}, WithMaxAttempts(2))
+
+	write(t, o, false, asynq.NewTask("a", nil), asynq.NewTask("b", nil))
+
+	for i := 0; i < 3; i++ {
+		if _, err := o.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var r struct {
+		Attempts int        `db:"attempts"`
+		FailedAt *time.Time `db:"failed_at"`
+	}
+
+	if err := o.db.Get(&r, `SELECT attempts, failed_at FROM gwf_outbox WHERE id = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed task is no longer tried once it reached the maximum
+	if r.Attempts != 2 || r.FailedAt == nil {
+		t.Errorf("attempts %d and failed at %v, want 2 and a time", r.Attempts, r.FailedAt)
+	}
+
+	if got := queued(m); !equal(got, []string{"b"}) {
+		t.Errorf("queued %v, want [b]", got)
+	}
+
+	// Clearing failed_at publishes the task again
+	if _, err := o.db.Exec(`UPDATE gwf_outbox SET failed_at = NULL WHERE id = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := o.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.db.Get(&r, `SELECT attempts, failed_at FROM gwf_outbox WHERE id = 'a'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Attempts != 3 || r.FailedAt == nil {
+		t.Errorf("attempts %d and failed at %v, want 3 and a time", r.Attempts, r.FailedAt)
+	}
+}
+
+func TestRelayAlreadyQueued(t *testing.T) {
+	o, m := newOutbox(t, nil)
+
+	// An earlier relay published the task but could not delete it
+	if _, err := m.Enqueue(context.Background(), asynq.NewTask("a", nil), asynq.TaskID("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, o, false, asynq.NewTask("a", nil))
+
+	if _, err := o.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pending(t, o); len(got) != 0 {
+		t.Errorf("pending %v, want none", got)
+	}
+}
+
+// writing writes a task to the outbox whenever a task is published, like a handler writing while the relay runs
+type writing struct {
+	*queue.Memory
+	outbox *Outbox
+	err    error
+}
+
+func (w *writing) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*queue.TaskInfo, error) {
+	tx, err := w.outbox.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := w.outbox.Tx(tx).Enqueue(ctx, asynq.NewTask("written", nil)); err != nil {
+		w.err = err
+	} else if err := tx.Commit(); err != nil {
+		w.err = err
+	}
+
+	return w.Memory.Enqueue(ctx, task, opts...)
+}
+
+func TestRelayDoesNotBlockWriters(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db")+"?_busy_timeout=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	backend := &writing{Memory: queue.NewMemory(queue.Synchronous())}
+	o := New(db, queue.New(backend).Client())
+	backend.outbox = o
+
+	if err := o.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, o, false, asynq.NewTask("a", nil))
+
+	if _, err := o.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.err != nil {
+		t.Fatalf("writing while relaying: %v", backend.err)
+	}
+
+	if got := pending(t, o); len(got) != 1 || got[0] == "a" {
+		t.Errorf("pending %v, want the task written while relaying", got)
+	}
+}
+
+func TestEnqueueConflict(t *testing.T) {
+	o, _ := newOutbox(t, nil)
+
+	ctx := context.Background()
+
+	tx, err := o.db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err := o.Enqueue(ctx, tx, asynq.NewTask("a", nil), asynq.TaskID("key")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := o.Enqueue(ctx, tx, asynq.NewTask("a", nil), asynq.TaskID("key")); !errors.Is(err, asynq.ErrTaskIDConflict) {
+		t.Errorf("got %v, want %v", err, asynq.ErrTaskIDConflict)
+	}
+
+	// The transaction is still usable
+	if _, err := o.Enqueue(ctx, tx, asynq.NewTask("a", nil), asynq.TaskID("other")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	at := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		opts []asynq.Option
+		want []asynq.Option
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "kept",
+			opts: []asynq.Option{
+				asynq.Queue("low"),
+				asynq.MaxRetry(0),
+				asynq.Timeout(time.Minute),
+				asynq.Deadline(at),
+				asynq.Unique(time.Hour),
+				asynq.ProcessAt(at),
+				asynq.Retention(time.Hour),
+				asynq.Group("g"),
+			},
+			want: []asynq.Option{
+				asynq.Queue("low"),
+				asynq.MaxRetry(0),
+				asynq.Timeout(time.Minute),
+				asynq.Deadline(at),
+				asynq.Unique(time.Hour),
+				asynq.ProcessAt(at),
+				asynq.Retention(time.Hour),
+				asynq.Group("g"),
+			},
+		},
+		{
+			name: "task id dropped",
+			opts: []asynq.Option{asynq.TaskID("id"), asynq.Queue("low")},
+			want: []asynq.Option{asynq.Queue("low")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := encodeOptions(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := decodeOptions(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d options, want %d", len(got), len(tt.want))
+			}
+
+			for i := range got {
+				if got[i].String() != tt.want[i].String() {
+					t.Errorf("got %s, want %s", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessInIsAbsolute(t *testing.T) {
+	s, err := encodeOptions([]asynq.Option{asynq.ProcessIn(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := decodeOptions(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opts) != 1 || opts[0].Type() != asynq.ProcessAtOpt {
+		t.Fatalf("got %v, want a ProcessAt option", opts)
+	}
+
+	if at := opts[0].Value().(time.Time); at.Before(time.Now().Add(59*time.Minute)) || at.After(time.Now().Add(time.Hour)) {
+		t.Errorf("processed at %s, want in an hour", at)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
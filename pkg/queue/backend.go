@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrShutdown is returned when enqueueing to a backend after it was shut down
+var ErrShutdown = errors.New("queue backend is shut down")
+
+// TaskInfo describes an enqueued task
+type TaskInfo = asynq.TaskInfo
+
+// Backend stores tasks and runs them with a handler, Redis runs them with asynq and Memory in process
+type Backend interface {
+	// Enqueue adds a task, options are the same as for asynq.Client.Enqueue
+	Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error)
+	// Task returns a task by ID, asynq.ErrTaskNotFound when it is unknown or deleted after it completed
+	Task(ctx context.Context, id string) (*TaskInfo, error)
+	// Start processes tasks with handler in the background
+	Start(handler asynq.Handler) error
+	// Shutdown stops processing and waits for active tasks
+	Shutdown()
+}
+
+type metaKey struct{}
+
+// taskMeta is what asynq puts in the handler's context, for backends other than Redis
+type taskMeta struct {
+	id       string
+	queue    string
+	retried  int
+	maxRetry int
+}
+
+func withMeta(ctx context.Context, m taskMeta) context.Context {
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
+func metaFrom(ctx context.Context) (taskMeta, bool) {
+	m, ok := ctx.Value(metaKey{}).(taskMeta)
+
+	return m, ok
+}
+
+// TaskID returns the ID of the task being processed, use it instead of asynq.GetTaskID to support every backend
+func TaskID(ctx context.Context) (string, bool) {
+	if m, ok := metaFrom(ctx); ok {
+		return m.id, true
+	}
+
+	return asynq.GetTaskID(ctx)
+}
+
+// RetryCount returns how many times the task being processed was retried
+func RetryCount(ctx context.Context) (int, bool) {
+	if m, ok := metaFrom(ctx); ok {
+		return m.retried, true
+	}
+
+	return asynq.GetRetryCount(ctx)
+}
+
+// MaxRetry returns how many times the task being processed can be retried
+func MaxRetry(ctx context.Context) (int, bool) {
+	if m, ok := metaFrom(ctx); ok {
+		return m.maxRetry, true
+	}
+
+	return asynq.GetMaxRetry(ctx)
+}
+
+// QueueName returns the queue of the task being processed
+func QueueName(ctx context.Context) (string, bool) {
+	if m, ok := metaFrom(ctx); ok {
+		return m.queue, true
+	}
+
+	return asynq.GetQueueName(ctx)
+}
@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Fixed retries after the same delay
+func Fixed(d time.Duration) asynq.RetryDelayFunc {
+	return func(int, error, *asynq.Task) time.Duration {
+		return d
+	}
+}
+
+// Exponential doubles the delay from base on every retry, up to max unless it is 0
+func Exponential(base, max time.Duration) asynq.RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		return exponential(base, max, n)
+	}
+}
+
+// Jittered waits a random delay up to the one of Exponential, spreading the retries of tasks that failed together
+func Jittered(base, max time.Duration) asynq.RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		d := exponential(base, max, n)
+		if d <= 0 {
+			return 0
+		}
+
+		jitterMu.Lock()
+		defer jitterMu.Unlock()
+
+		return time.Duration(jitterRand.Int63n(int64(d))) + 1
+	}
+}
+
+func exponential(base, max time.Duration, n int) time.Duration {
+	d := base
+
+	for i := 0; i < n; i++ {
+		if d > math.MaxInt64/2 || (max > 0 && d >= max) {
+			break
+		}
+
+		d *= 2
+	}
+
+	if max > 0 && d > max {
+		return max
+	}
+
+	return d
+}
@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		base time.Duration
+		max  time.Duration
+		n    int
+		want time.Duration
+	}{
+		{"first retry", time.Second, time.Hour, 0, time.Second},
+		{"doubled", time.Second, time.Hour, 3, 8 * time.Second},
+		{"capped", time.Second, time.Minute, 6, time.Minute},
+		{"capped far beyond", time.Second, time.Minute, 1000, time.Minute},
+		{"max below base", time.Minute, time.Second, 0, time.Second},
+		{"uncapped", time.Second, 0, 10, 1024 * time.Second},
+		{"uncapped without overflow", time.Second, 0, 1000, time.Second << 33},
+		{"large base without overflow", time.Duration(math.MaxInt64 / 3), 0, 5, time.Duration(math.MaxInt64/3) * 2},
+		{"zero base", 0, time.Minute, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Exponential(tt.base, tt.max)(tt.n, nil, nil); got != tt.want {
+				t.Errorf("exponential %s, want %s", got, tt.want)
+			}
+
+			if got := Fixed(tt.base)(tt.n, nil, nil); got != tt.base {
+				t.Errorf("fixed %s, want %s", got, tt.base)
+			}
+
+			for i := 0; i < 100; i++ {
+				got := Jittered(tt.base, tt.max)(tt.n, nil, nil)
+
+				if tt.want == 0 && got != 0 {
+					t.Fatalf("jittered %s, want 0", got)
+				}
+
+				if tt.want > 0 && (got < 1 || got > tt.want) {
+					t.Fatalf("jittered %s, want between 1ns and %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
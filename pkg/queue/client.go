@@ -0,0 +1,206 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	ctxlog "github.com/khvh/gwf/pkg/logger"
+)
+
+var enqueueFailedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "enqueue_failures_total",
+		Help: "The total number of tasks that could not be enqueued",
+	},
+	[]string{"task_type"},
+)
+
+// Client enqueues tasks to a backend
+type Client struct {
+	backend  Backend
+	mu       sync.RWMutex
+	defaults map[string][]asynq.Option
+}
+
+var (
+	clientMu       sync.RWMutex
+	clientInstance *Client
+)
+
+func newClient(backend Backend) *Client {
+	return &Client{
+		backend:  backend,
+		defaults: map[string][]asynq.Option{},
+	}
+}
+
+// NewClient creates a client for the Redis at redisAddress.
+// It becomes the default client used by Task.Enqueue and Inspect when there is none yet, see SetDefaultClient.
+func NewClient(redisAddress string) *Client {
+	c := newClient(NewRedis(asynq.RedisClientOpt{Addr: redisAddress}, 0, nil))
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if clientInstance == nil {
+		clientInstance = c
+	}
+
+	return c
+}
+
+// SetDefaultClient sets the client used by Task.Enqueue and Inspect, New sets the client of the queue
+func SetDefaultClient(c *Client) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	clientInstance = c
+}
+
+// DefaultClient returns the client used by Task.Enqueue and Inspect, nil when none was created
+func DefaultClient() *Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+
+	return clientInstance
+}
+
+// Defaults sets the options of a task type, options of typed task definitions and of Enqueue override them
+func (c *Client) Defaults(typename string, opts ...asynq.Option) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.defaults[typename] = opts
+
+	return c
+}
+
+// Enqueue adds a task to the queue, logging with the request ID of ctx.
+// JSON object payloads carry the trace context and request ID of ctx to the handler unless they already carry ones,
+// the task is created again then, give options to Enqueue rather than to asynq.NewTask.
+func (c *Client) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error) {
+	task = withEnvelope(ctx, task)
+
+	info, err := c.backend.Enqueue(ctx, task, c.options(task.Type(), opts)...)
+	if errors.Is(err, asynq.ErrDuplicateTask) || errors.Is(err, asynq.ErrTaskIDConflict) {
+		ctxlog.Ctx(ctx).Trace().Str("task", task.Type()).Msg("Skipped a duplicate task")
+
+		return nil, fmt.Errorf("enqueueing %s: %w", task.Type(), err)
+	}
+
+	if err != nil {
+		enqueueFailedCounter.WithLabelValues(task.Type()).Inc()
+
+		ctxlog.Ctx(ctx).Error().Err(err).Str("task", task.Type()).Msg("While enqueueing a task")
+
+		return nil, fmt.Errorf("enqueueing %s: %w", task.Type(), err)
+	}
+
+	ctxlog.Ctx(ctx).Trace().Msgf("Added task [%s] to [%s]", info.ID, info.Queue)
+
+	return info, nil
+}
+
+// EnqueueBatch adds every task with the same options, a failing task does not stop the rest.
+// Infos are returned in the order of tasks, nil for tasks that failed, which are listed in a *BatchError.
+func (c *Client) EnqueueBatch(ctx context.Context, tasks []*asynq.Task, opts ...asynq.Option) ([]*TaskInfo, error) {
+	tx := c.Begin()
+
+	for _, task := range tasks {
+		tx.Enqueue(task, opts...)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Add a new task to queue, it returns the ID of the task
+func (c *Client) Add(task *asynq.Task, opts ...asynq.Option) (string, error) {
+	return c.AddContext(context.Background(), task, opts...)
+}
+
+// AddContext adds a new task to queue like Enqueue, it returns the ID of the task
+func (c *Client) AddContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (string, error) {
+	info, err := c.Enqueue(ctx, task, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return info.ID, nil
+}
+
+// Inspect returns the state of a task and its result, asynq.ErrTaskNotFound when it is unknown or deleted after it completed
+func (c *Client) Inspect(ctx context.Context, id string) (*TaskInfo, error) {
+	return c.backend.Task(ctx, id)
+}
+
+// options returns the defaults of the task type followed by opts, with relative deadlines resolved
+func (c *Client) options(typename string, opts []asynq.Option) []asynq.Option {
+	c.mu.RLock()
+	res := append([]asynq.Option{}, c.defaults[typename]...)
+	c.mu.RUnlock()
+
+	res = append(res, opts...)
+
+	for i, opt := range res {
+		if d, ok := opt.(deadlineIn); ok {
+			res[i] = asynq.Deadline(time.Now().Add(time.Duration(d)))
+		}
+	}
+
+	return res
+}
+
+// DeadlineIn is like asynq.Deadline relative to when the task is enqueued, e.g. for Client.Defaults.
+// It only works with Client.Enqueue and typed tasks.
+func DeadlineIn(d time.Duration) asynq.Option {
+	return deadlineIn(d)
+}
+
+type deadlineIn time.Duration
+
+func (d deadlineIn) String() string {
+	return fmt.Sprintf("DeadlineIn(%v)", time.Duration(d))
+}
+
+func (d deadlineIn) Type() asynq.OptionType {
+	return asynq.DeadlineOpt
+}
+
+func (d deadlineIn) Value() interface{} {
+	return time.Now().Add(time.Duration(d))
+}
+
+// BatchError is returned by EnqueueBatch when tasks could not be enqueued
+type BatchError struct {
+	Total  int
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d tasks were not enqueued: %v", len(e.Errors), e.Total, e.Unwrap())
+}
+
+// Unwrap returns the error of the first failed task
+func (e *BatchError) Unwrap() error {
+	indexes := make([]int, 0, len(e.Errors))
+
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	return e.Errors[indexes[0]]
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+
+	ctxlog "github.com/khvh/gwf/pkg/logger"
+)
+
+func TestDefaultClient(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	SetDefaultClient(nil)
+
+	first := NewClient("10.0.0.1:6379")
+	second := NewClient("10.0.0.2:6379")
+
+	for addr, c := range map[string]*Client{"10.0.0.1:6379": first, "10.0.0.2:6379": second} {
+		if got := c.backend.(*Redis).Conn().Addr; got != addr {
+			t.Errorf("client connects to %s, want %s", got, addr)
+		}
+	}
+
+	if DefaultClient() != first {
+		t.Error("the first client is not the default one")
+	}
+
+	q := New(NewMemory(Synchronous()))
+
+	if DefaultClient() != q.Client() {
+		t.Error("the client of the queue is not the default one")
+	}
+
+	SetDefaultClient(second)
+
+	if DefaultClient() != second {
+		t.Error("SetDefaultClient did not replace the default client")
+	}
+}
+
+func TestEnqueueWithoutClient(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	SetDefaultClient(nil)
+
+	if _, err := NewTask[string]("test").Enqueue(context.Background(), "x"); !errors.Is(err, ErrNoClient) {
+		t.Errorf("got %v, want %v", err, ErrNoClient)
+	}
+}
+
+func TestClientDefaults(t *testing.T) {
+	m := NewMemory(Synchronous())
+	c := New(m).Client()
+
+	c.Defaults("test", asynq.Queue("low"), asynq.MaxRetry(3))
+
+	info, err := c.Enqueue(context.Background(), asynq.NewTask("test", nil), asynq.MaxRetry(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Queue != "low" || info.MaxRetry != 1 {
+		t.Errorf("queue %s and max retry %d, want low and 1", info.Queue, info.MaxRetry)
+	}
+}
+
+func TestEnqueuePlainTask(t *testing.T) {
+	m := NewMemory(Synchronous())
+
+	var got []string
+
+	q := New(m).AddHandlerFunc("test:plain", func(ctx context.Context, task *asynq.Task) error {
+		got = append(got, string(Payload(task))+" "+ctxlog.RequestID(ctx))
+
+		return nil
+	})
+
+	q.Run()
+	defer q.Shutdown()
+
+	ctx := ctxlog.WithRequestID(context.Background(), "request-1")
+
+	if _, err := q.Client().AddContext(ctx, asynq.NewTask("test:plain", []byte(`{"id":1}`))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The envelope of the producer is kept
+	if _, err := q.Client().Enqueue(ctxlog.WithRequestID(context.Background(), "request-2"), NewTaskContext(ctx, "test:plain", []byte(`{"id":2}`))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`{"id":1} request-1`, `{"id":2} request-1`}
+
+	if len(got) != len(want) {
+		t.Fatalf("handler got %q, want %q", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("handler got %q, want %q", got[i], want[i])
+		}
+	}
+}
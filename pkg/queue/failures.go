@@ -0,0 +1,260 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var archivedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "archived_tasks_total",
+		Help: "The total number of tasks archived after their last attempt failed",
+	},
+	[]string{"task_type", "error_class"},
+)
+
+// Error classes of Classify
+const (
+	ClassError   = "error"
+	ClassSkip    = "skip"
+	ClassTimeout = "timeout"
+)
+
+// Classifier maps the error of a failed attempt to a class, used in metrics and callbacks, and whether to retry the task
+type Classifier func(err error) (class string, retry bool)
+
+// Classify is the default Classifier, errors wrapping asynq.SkipRetry are not retried
+func Classify(err error) (string, bool) {
+	switch {
+	case errors.Is(err, asynq.SkipRetry):
+		return ClassSkip, false
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout, true
+	}
+
+	return ClassError, true
+}
+
+// Failure describes a failed attempt to process a task
+type Failure struct {
+	Task     *asynq.Task
+	ID       string
+	Queue    string
+	Err      error
+	Class    string
+	Retried  int
+	MaxRetry int
+	// Archived is set on the last attempt, the task is not retried anymore
+	Archived bool
+}
+
+// FailureFunc is called with the context of the task, which is done when the task timed out
+type FailureFunc func(ctx context.Context, f *Failure)
+
+type policy struct {
+	backoff    asynq.RetryDelayFunc
+	classify   Classifier
+	onFailure  []FailureFunc
+	onArchived []FailureFunc
+}
+
+// PolicyOption configures how failures of a task type are handled, see Queue.Policy
+type PolicyOption func(p *policy)
+
+// WithBackoff sets the delay before retrying, e.g. Fixed, Exponential or Jittered
+func WithBackoff(fn asynq.RetryDelayFunc) PolicyOption {
+	return func(p *policy) {
+		p.backoff = fn
+	}
+}
+
+// WithClassifier sets how errors are classified, Classify by default.
+// Errors wrapping asynq.SkipRetry are never retried whatever the classifier returns.
+func WithClassifier(fn Classifier) PolicyOption {
+	return func(p *policy) {
+		p.classify = fn
+	}
+}
+
+// OnFailure adds a callback called after every failed attempt
+func OnFailure(fn FailureFunc) PolicyOption {
+	return func(p *policy) {
+		p.onFailure = append(p.onFailure, fn)
+	}
+}
+
+// OnArchived adds a callback called when the task is archived, after its retries are exhausted or it is not retried
+func OnArchived(fn FailureFunc) PolicyOption {
+	return func(p *policy) {
+		p.onArchived = append(p.onArchived, fn)
+	}
+}
+
+// policies holds the failure policies by task type, the one of the empty type applies to every type
+type policies struct {
+	mu sync.RWMutex
+	m  map[string]*policy
+}
+
+func newPolicies() *policies {
+	return &policies{
+		m: map[string]*policy{},
+	}
+}
+
+func (p *policies) set(typename string, opts []PolicyOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pol, ok := p.m[typename]
+	if !ok {
+		pol = &policy{}
+		p.m[typename] = pol
+	}
+
+	for _, opt := range opts {
+		opt(pol)
+	}
+}
+
+// get returns the policy of the type merged with the default one
+func (p *policies) get(typename string) *policy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	res := &policy{
+		classify: Classify,
+	}
+
+	for _, name := range []string{"", typename} {
+		pol, ok := p.m[name]
+		if !ok {
+			continue
+		}
+
+		if pol.backoff != nil {
+			res.backoff = pol.backoff
+		}
+
+		if pol.classify != nil {
+			res.classify = pol.classify
+		}
+
+		res.onFailure = append(res.onFailure, pol.onFailure...)
+		res.onArchived = append(res.onArchived, pol.onArchived...)
+	}
+
+	return res
+}
+
+// retryDelay returns a delay func using the backoff of the task type, fallback when it has none
+func (p *policies) retryDelay(fallback asynq.RetryDelayFunc) asynq.RetryDelayFunc {
+	if fallback == nil {
+		fallback = asynq.DefaultRetryDelayFunc
+	}
+
+	return func(n int, err error, t *asynq.Task) time.Duration {
+		if fn := p.get(t.Type()).backoff; fn != nil {
+			return fn(n, err, t)
+		}
+
+		return fallback(n, err, t)
+	}
+}
+
+type classifierKey struct{}
+
+// WillRetry reports whether the task being processed is retried after its handler returns err, applying the
+// classifier of its policy, e.g. for handlers that record a failure only once the task is not retried anymore
+func WillRetry(ctx context.Context, err error) bool {
+	if errors.Is(err, asynq.SkipRetry) {
+		return false
+	}
+
+	if classify, ok := ctx.Value(classifierKey{}).(Classifier); ok {
+		if _, retry := classify(err); !retry {
+			return false
+		}
+	}
+
+	retried, _ := RetryCount(ctx)
+	maxRetry, _ := MaxRetry(ctx)
+
+	return retried < maxRetry
+}
+
+// handle classifies the errors of handlers and calls the failure callbacks
+func (p *policies) handle(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		pol := p.get(t.Type())
+
+		err := next.ProcessTask(context.WithValue(ctx, classifierKey{}, pol.classify), t)
+		if err == nil {
+			return nil
+		}
+
+		class, retry := pol.classify(err)
+
+		if !retry && !errors.Is(err, asynq.SkipRetry) {
+			err = skipRetry{err}
+		}
+
+		id, _ := TaskID(ctx)
+		queue, _ := QueueName(ctx)
+		retried, _ := RetryCount(ctx)
+		maxRetry, _ := MaxRetry(ctx)
+
+		f := &Failure{
+			Task:     t,
+			ID:       id,
+			Queue:    queue,
+			Err:      err,
+			Class:    class,
+			Retried:  retried,
+			MaxRetry: maxRetry,
+			Archived: errors.Is(err, asynq.SkipRetry) || retried >= maxRetry,
+		}
+
+		for _, fn := range pol.onFailure {
+			fn(ctx, f)
+		}
+
+		if f.Archived {
+			archivedCounter.WithLabelValues(t.Type(), class).Inc()
+
+			for _, fn := range pol.onArchived {
+				fn(ctx, f)
+			}
+		}
+
+		return err
+	})
+}
+
+// skipRetry marks an error the classifier does not retry, keeping the original error
+type skipRetry struct {
+	err error
+}
+
+func (e skipRetry) Error() string {
+	return e.err.Error()
+}
+
+func (e skipRetry) Unwrap() error {
+	return e.err
+}
+
+func (e skipRetry) Is(target error) bool {
+	return target == asynq.SkipRetry
+}
+
+// retryDelayer is implemented by backends retrying with a delay func, Queue replaces it to apply the backoff of policies
+type retryDelayer interface {
+	wrapRetryDelay(wrap func(asynq.RetryDelayFunc) asynq.RetryDelayFunc)
+}
@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+var (
+	errFailed    = errors.New("failed")
+	errPermanent = errors.New("permanent")
+)
+
+func classifyPermanent(err error) (string, bool) {
+	if errors.Is(err, errPermanent) {
+		return "permanent", false
+	}
+
+	return Classify(err)
+}
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		maxRetry int
+		opts     []PolicyOption
+		// retries is the number of times the clock is advanced and the queue drained after the first attempt
+		retries   int
+		runs      int
+		willRetry []bool
+		class     string
+		archived  bool
+	}{
+		{
+			name:      "retried",
+			err:       errFailed,
+			maxRetry:  1,
+			retries:   1,
+			runs:      2,
+			willRetry: []bool{true, false},
+			class:     ClassError,
+			archived:  true,
+		},
+		{
+			name:      "skipped",
+			err:       fmt.Errorf("invalid: %w", asynq.SkipRetry),
+			maxRetry:  3,
+			retries:   1,
+			runs:      1,
+			willRetry: []bool{false},
+			class:     ClassSkip,
+			archived:  true,
+		},
+		{
+			name:      "classified as not retryable",
+			err:       fmt.Errorf("wrapped: %w", errPermanent),
+			maxRetry:  3,
+			opts:      []PolicyOption{WithClassifier(classifyPermanent)},
+			retries:   1,
+			runs:      1,
+			willRetry: []bool{false},
+			class:     "permanent",
+			archived:  true,
+		},
+		{
+			name:      "classified as retryable",
+			err:       errFailed,
+			maxRetry:  3,
+			opts:      []PolicyOption{WithClassifier(classifyPermanent)},
+			retries:   1,
+			runs:      2,
+			willRetry: []bool{true, true},
+			class:     ClassError,
+		},
+		{
+			name:      "backoff of the policy",
+			err:       errFailed,
+			maxRetry:  3,
+			opts:      []PolicyOption{WithBackoff(Fixed(2 * time.Minute))},
+			retries:   1,
+			runs:      1,
+			willRetry: []bool{true},
+			class:     ClassError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(Synchronous(), WithRetryDelay(Fixed(time.Minute)))
+
+			var (
+				willRetry []bool
+				failures  []*Failure
+				archived  []*Failure
+			)
+
+			q := New(m).
+				AddHandlerFunc("test", func(ctx context.Context, task *asynq.Task) error {
+					willRetry = append(willRetry, WillRetry(ctx, tt.err))
+
+					return tt.err
+				}).
+				Policy("test", append(tt.opts,
+					OnFailure(func(_ context.Context, f *Failure) { failures = append(failures, f) }),
+					OnArchived(func(_ context.Context, f *Failure) { archived = append(archived, f) }),
+				)...)
+
+			q.Run()
+			defer q.Shutdown()
+
+			ctx := context.Background()
+
+			if _, err := q.Client().Enqueue(ctx, asynq.NewTask("test", nil), asynq.MaxRetry(tt.maxRetry)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.Drain(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < tt.retries; i++ {
+				m.Advance(time.Minute)
+
+				if err := m.Drain(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(willRetry) != tt.runs || len(failures) != tt.runs {
+				t.Fatalf("processed %d times with %d failures, want %d", len(willRetry), len(failures), tt.runs)
+			}
+
+			for i, retry := range willRetry {
+				if retry != tt.willRetry[i] {
+					t.Errorf("attempt %d: WillRetry %t, want %t", i+1, retry, tt.willRetry[i])
+				}
+
+				if failures[i].Archived == retry {
+					t.Errorf("attempt %d: archived %t while WillRetry is %t", i+1, failures[i].Archived, retry)
+				}
+			}
+
+			if last := failures[len(failures)-1]; last.Class != tt.class {
+				t.Errorf("class %s, want %s", last.Class, tt.class)
+			}
+
+			if (len(archived) == 1) != tt.archived {
+				t.Errorf("archived %d times, want archived %t", len(archived), tt.archived)
+			}
+		})
+	}
+}
+
+func TestWillRetryOutsidePolicies(t *testing.T) {
+	ctx := withMeta(context.Background(), taskMeta{retried: 1, maxRetry: 2})
+
+	if !WillRetry(ctx, errPermanent) {
+		t.Error("WillRetry is false without a classifier")
+	}
+
+	if WillRetry(ctx, fmt.Errorf("%v: %w", errPermanent, asynq.SkipRetry)) {
+		t.Error("WillRetry is true for SkipRetry")
+	}
+
+	if WillRetry(withMeta(context.Background(), taskMeta{retried: 2, maxRetry: 2}), errPermanent) {
+		t.Error("WillRetry is true after the last retry")
+	}
+}
@@ -0,0 +1,487 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// ErrNotStarted is returned by Memory.Drain before a handler was given with Start
+var ErrNotStarted = errors.New("memory queue is not started")
+
+const (
+	defaultMaxRetry = 25
+	defaultTimeout  = 30 * time.Minute
+	pollInterval    = 100 * time.Millisecond
+)
+
+// MemoryOption configures a Memory backend
+type MemoryOption func(m *Memory)
+
+// WithConcurrency sets the number of workers, the number of CPUs by default
+func WithConcurrency(n int) MemoryOption {
+	return func(m *Memory) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithQueues sets the queue priorities, queues with a higher priority are always processed first
+func WithQueues(qs Queues) MemoryOption {
+	return func(m *Memory) {
+		if len(qs) > 0 {
+			m.queues = qs
+		}
+	}
+}
+
+// WithRetryDelay sets how long to wait before retrying a failed task, asynq.DefaultRetryDelayFunc by default
+func WithRetryDelay(fn asynq.RetryDelayFunc) MemoryOption {
+	return func(m *Memory) {
+		m.retryDelay = fn
+	}
+}
+
+// Synchronous disables the workers, tasks are only processed by Drain, e.g. in tests
+func Synchronous() MemoryOption {
+	return func(m *Memory) {
+		m.concurrency = 0
+	}
+}
+
+// Memory is an in-process backend for tests and local development, tasks are lost when the process exits.
+// It supports the queue, retry, timeout, deadline, unique, process at/in, task ID and retention options and results.
+type Memory struct {
+	concurrency int
+	queues      Queues
+	retryDelay  asynq.RetryDelayFunc
+
+	mu      sync.Mutex
+	offset  time.Duration
+	tasks   map[string]*memoryTask
+	order   []string
+	unique  map[string]time.Time
+	handler asynq.Handler
+	wake    chan struct{}
+	stop    chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+type memoryTask struct {
+	task   *asynq.Task
+	info   TaskInfo
+	unique string
+}
+
+// NewMemory creates an in-process backend
+func NewMemory(opts ...MemoryOption) *Memory {
+	m := &Memory{
+		concurrency: runtime.NumCPU(),
+		queues:      Queues{"default": 1},
+		retryDelay:  asynq.DefaultRetryDelayFunc,
+		tasks:       map[string]*memoryTask{},
+		unique:      map[string]time.Time{},
+		wake:        make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Enqueue adds a task, it is processed right away unless it is delayed with asynq.ProcessAt or asynq.ProcessIn.
+// It returns ErrShutdown after Shutdown, like Redis.
+func (m *Memory) Enqueue(_ context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error) {
+	if task == nil || task.Type() == "" {
+		return nil, fmt.Errorf("task typename cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrShutdown
+	}
+
+	now := m.now()
+
+	t := &memoryTask{
+		task: task,
+		info: TaskInfo{
+			ID:            uuid.NewString(),
+			Queue:         "default",
+			Type:          task.Type(),
+			Payload:       task.Payload(),
+			State:         asynq.TaskStatePending,
+			MaxRetry:      defaultMaxRetry,
+			NextProcessAt: now,
+		},
+	}
+
+	var uniqueTTL time.Duration
+
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.MaxRetryOpt:
+			t.info.MaxRetry = opt.Value().(int)
+		case asynq.QueueOpt:
+			t.info.Queue = opt.Value().(string)
+		case asynq.TimeoutOpt:
+			t.info.Timeout = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			t.info.Deadline = opt.Value().(time.Time)
+		case asynq.UniqueOpt:
+			uniqueTTL = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			t.info.NextProcessAt = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			t.info.NextProcessAt = now.Add(opt.Value().(time.Duration))
+		case asynq.TaskIDOpt:
+			t.info.ID = opt.Value().(string)
+		case asynq.RetentionOpt:
+			t.info.Retention = opt.Value().(time.Duration)
+		case asynq.GroupOpt:
+			t.info.Group = opt.Value().(string)
+		}
+	}
+
+	if t.info.MaxRetry < 0 {
+		t.info.MaxRetry = 0
+	}
+
+	if t.info.Timeout == 0 && t.info.Deadline.IsZero() {
+		t.info.Timeout = defaultTimeout
+	}
+
+	if t.info.NextProcessAt.After(now) {
+		t.info.State = asynq.TaskStateScheduled
+	}
+
+	if _, ok := m.tasks[t.info.ID]; ok {
+		return nil, asynq.ErrTaskIDConflict
+	}
+
+	if uniqueTTL > 0 {
+		t.unique = uniqueKey(t.info.Queue, t.info.Type, t.info.Payload)
+
+		if until, ok := m.unique[t.unique]; ok && until.After(now) {
+			return nil, asynq.ErrDuplicateTask
+		}
+
+		m.unique[t.unique] = now.Add(uniqueTTL)
+	}
+
+	m.tasks[t.info.ID] = t
+	m.order = append(m.order, t.info.ID)
+
+	m.notify()
+
+	info := t.info
+
+	return &info, nil
+}
+
+// Start processes tasks with handler using the configured number of workers
+func (m *Memory) Start(handler asynq.Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.handler != nil {
+		return fmt.Errorf("memory queue is already started")
+	}
+
+	m.handler = handler
+	m.stop = make(chan struct{})
+
+	for i := 0; i < m.concurrency; i++ {
+		m.wg.Add(1)
+
+		go m.work(m.stop)
+	}
+
+	return nil
+}
+
+// Shutdown stops the workers and waits for active tasks, enqueueing afterwards returns ErrShutdown
+func (m *Memory) Shutdown() {
+	m.mu.Lock()
+
+	m.closed = true
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+
+	m.handler = nil
+
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Drain processes tasks one by one until none is due, delayed tasks and retries become due with Advance
+func (m *Memory) Drain(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		handler := m.handler
+		t := m.next()
+		m.mu.Unlock()
+
+		if handler == nil {
+			return ErrNotStarted
+		}
+
+		if t == nil {
+			return nil
+		}
+
+		m.process(ctx, handler, t)
+	}
+}
+
+// Advance moves the clock of the backend forward, making delayed tasks and retries due sooner
+func (m *Memory) Advance(d time.Duration) {
+	m.mu.Lock()
+	m.offset += d
+	m.notify()
+	m.mu.Unlock()
+}
+
+// Tasks returns every task that is not deleted yet, completed tasks are kept for their retention, archived ones until the process exits
+func (m *Memory) Tasks() []*TaskInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]*TaskInfo, 0, len(m.order))
+
+	for _, id := range m.order {
+		info := m.tasks[id].info
+		res = append(res, &info)
+	}
+
+	return res
+}
+
+// Task returns a task that is not deleted yet
+func (m *Memory) Task(_ context.Context, id string) (*TaskInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(m.now())
+
+	t, ok := m.tasks[id]
+	if !ok {
+		return nil, asynq.ErrTaskNotFound
+	}
+
+	info := t.info
+
+	return &info, nil
+}
+
+func (m *Memory) wrapRetryDelay(wrap func(asynq.RetryDelayFunc) asynq.RetryDelayFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retryDelay = wrap(m.retryDelay)
+}
+
+func (m *Memory) now() time.Time {
+	return time.Now().Add(m.offset)
+}
+
+func (m *Memory) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work processes due tasks until stop is closed, it is given by Start as Shutdown may run before the worker does
+func (m *Memory) work(stop chan struct{}) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.wake:
+		case <-ticker.C:
+		}
+
+		for {
+			m.mu.Lock()
+			handler := m.handler
+			t := m.next()
+			m.mu.Unlock()
+
+			if handler == nil || t == nil {
+				break
+			}
+
+			// Wake another worker in case more tasks are due
+			m.notify()
+
+			m.process(context.Background(), handler, t)
+		}
+	}
+}
+
+// next marks the due task of the highest priority queue active and returns it, m.mu must be held
+func (m *Memory) next() *memoryTask {
+	now := m.now()
+
+	m.expire(now)
+
+	var due []*memoryTask
+
+	for _, id := range m.order {
+		t := m.tasks[id]
+
+		switch t.info.State {
+		case asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry:
+			if !t.info.NextProcessAt.After(now) {
+				due = append(due, t)
+			}
+		}
+	}
+
+	if len(due) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return m.queues[due[i].info.Queue] > m.queues[due[j].info.Queue]
+	})
+
+	t := due[0]
+	t.info.State = asynq.TaskStateActive
+
+	return t
+}
+
+// expire deletes completed tasks past their retention and unique locks past their TTL, m.mu must be held
+func (m *Memory) expire(now time.Time) {
+	for key, until := range m.unique {
+		if !until.After(now) {
+			delete(m.unique, key)
+		}
+	}
+
+	for _, id := range m.order {
+		t := m.tasks[id]
+
+		if t.info.State == asynq.TaskStateCompleted && !t.info.CompletedAt.Add(t.info.Retention).After(now) {
+			m.delete(id)
+		}
+	}
+}
+
+// delete removes a task, m.mu must be held
+func (m *Memory) delete(id string) {
+	delete(m.tasks, id)
+
+	for i, v := range m.order {
+		if v == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+
+			break
+		}
+	}
+}
+
+func (m *Memory) process(ctx context.Context, handler asynq.Handler, t *memoryTask) {
+	m.mu.Lock()
+	info := t.info
+	deadline := info.Deadline
+	m.mu.Unlock()
+
+	if info.Timeout > 0 && (deadline.IsZero() || time.Now().Add(info.Timeout).Before(deadline)) {
+		deadline = time.Now().Add(info.Timeout)
+	}
+
+	ctx, cancel := context.WithDeadline(withMeta(ctx, taskMeta{
+		id:       info.ID,
+		queue:    info.Queue,
+		retried:  info.Retried,
+		maxRetry: info.MaxRetry,
+	}), deadline)
+	defer cancel()
+
+	ctx = withResultWriter(ctx, func(b []byte) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		t.info.Result = append([]byte{}, b...)
+
+		return nil
+	})
+
+	err := run(ctx, handler, t.task)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	if err == nil {
+		if t.unique != "" {
+			delete(m.unique, t.unique)
+		}
+
+		if t.info.Retention <= 0 {
+			m.delete(t.info.ID)
+
+			return
+		}
+
+		t.info.State = asynq.TaskStateCompleted
+		t.info.CompletedAt = now
+
+		return
+	}
+
+	t.info.LastErr = err.Error()
+	t.info.LastFailedAt = now
+
+	// Like asynq, archiving keeps the unique lock until its TTL expires
+	if errors.Is(err, asynq.SkipRetry) || t.info.Retried >= t.info.MaxRetry {
+		t.info.State = asynq.TaskStateArchived
+
+		return
+	}
+
+	t.info.State = asynq.TaskStateRetry
+	t.info.NextProcessAt = now.Add(m.retryDelay(t.info.Retried, err, t.task))
+	t.info.Retried++
+}
+
+// run calls the handler, turning a panic into an error like asynq does
+func run(ctx context.Context, handler asynq.Handler, task *asynq.Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler.ProcessTask(ctx, task)
+}
@@ -0,0 +1,289 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestMemory(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name string
+		// fails reports whether attempt n fails
+		fails func(n int) bool
+		opts  []asynq.Option
+		// steps advance the clock and drain after the first drain
+		steps []time.Duration
+		state asynq.TaskState
+		runs  int
+	}{
+		{
+			name:  "processed and deleted",
+			fails: func(int) bool { return false },
+			runs:  1,
+		},
+		{
+			name:  "kept for retention",
+			fails: func(int) bool { return false },
+			opts:  []asynq.Option{asynq.Retention(time.Hour)},
+			state: asynq.TaskStateCompleted,
+			runs:  1,
+		},
+		{
+			name:  "deleted after retention",
+			fails: func(int) bool { return false },
+			opts:  []asynq.Option{asynq.Retention(time.Hour)},
+			steps: []time.Duration{2 * time.Hour},
+			runs:  1,
+		},
+		{
+			name:  "retried after the delay",
+			fails: func(n int) bool { return n == 1 },
+			opts:  []asynq.Option{asynq.Retention(time.Hour)},
+			steps: []time.Duration{30 * time.Second, time.Minute},
+			state: asynq.TaskStateCompleted,
+			runs:  2,
+		},
+		{
+			name:  "not retried before the delay",
+			fails: func(n int) bool { return n == 1 },
+			steps: []time.Duration{30 * time.Second},
+			state: asynq.TaskStateRetry,
+			runs:  1,
+		},
+		{
+			name:  "archived when retries are exhausted",
+			fails: func(int) bool { return true },
+			opts:  []asynq.Option{asynq.MaxRetry(2)},
+			steps: []time.Duration{time.Minute, time.Minute, time.Minute},
+			state: asynq.TaskStateArchived,
+			runs:  3,
+		},
+		{
+			name:  "delayed",
+			fails: func(int) bool { return false },
+			opts:  []asynq.Option{asynq.ProcessIn(time.Hour), asynq.Retention(time.Hour)},
+			steps: []time.Duration{30 * time.Minute},
+			state: asynq.TaskStateScheduled,
+		},
+		{
+			name:  "delayed until due",
+			fails: func(int) bool { return false },
+			opts:  []asynq.Option{asynq.ProcessIn(time.Hour), asynq.Retention(time.Hour)},
+			steps: []time.Duration{30 * time.Minute, 30 * time.Minute},
+			state: asynq.TaskStateCompleted,
+			runs:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(Synchronous(), WithRetryDelay(Fixed(time.Minute)))
+			runs := 0
+
+			if err := m.Start(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+				runs++
+
+				if tt.fails(runs) {
+					return errFailed
+				}
+
+				return nil
+			})); err != nil {
+				t.Fatal(err)
+			}
+
+			defer m.Shutdown()
+
+			ctx := context.Background()
+
+			info, err := m.Enqueue(ctx, asynq.NewTask("test", nil), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.Drain(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, d := range tt.steps {
+				m.Advance(d)
+
+				if err := m.Drain(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if runs != tt.runs {
+				t.Errorf("processed %d times, want %d", runs, tt.runs)
+			}
+
+			got, err := m.Task(ctx, info.ID)
+
+			if tt.state == 0 {
+				if !errors.Is(err, asynq.ErrTaskNotFound) {
+					t.Errorf("got %v, want the task to be deleted", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.State != tt.state {
+				t.Errorf("state %s, want %s", got.State, tt.state)
+			}
+		})
+	}
+}
+
+func TestMemoryUnique(t *testing.T) {
+	m := NewMemory(Synchronous())
+	ctx := context.Background()
+
+	if err := m.Start(asynq.HandlerFunc(func(context.Context, *asynq.Task) error { return nil })); err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Shutdown()
+
+	enqueue := func(payload string) error {
+		_, err := m.Enqueue(ctx, asynq.NewTask("test", []byte(payload)), asynq.Unique(time.Minute))
+
+		return err
+	}
+
+	if err := enqueue("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enqueue("a"); !errors.Is(err, asynq.ErrDuplicateTask) {
+		t.Errorf("got %v, want %v", err, asynq.ErrDuplicateTask)
+	}
+
+	if err := enqueue("b"); err != nil {
+		t.Errorf("another payload: %v", err)
+	}
+
+	// The lock is released once the task is processed successfully
+	if err := m.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enqueue("a"); err != nil {
+		t.Errorf("after processing: %v", err)
+	}
+
+	// and after the TTL otherwise
+	m.Advance(2 * time.Minute)
+
+	if _, err := m.Enqueue(ctx, asynq.NewTask("test", []byte("c")), asynq.Unique(time.Minute), asynq.ProcessIn(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Advance(2 * time.Minute)
+
+	if err := enqueue("c"); err != nil {
+		t.Errorf("after the TTL: %v", err)
+	}
+}
+
+func TestMemoryTaskIDConflict(t *testing.T) {
+	m := NewMemory(Synchronous())
+	ctx := context.Background()
+
+	if _, err := m.Enqueue(ctx, asynq.NewTask("test", nil), asynq.TaskID("id")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Enqueue(ctx, asynq.NewTask("test", nil), asynq.TaskID("id")); !errors.Is(err, asynq.ErrTaskIDConflict) {
+		t.Errorf("got %v, want %v", err, asynq.ErrTaskIDConflict)
+	}
+}
+
+func TestMemoryDrainNotStarted(t *testing.T) {
+	if err := NewMemory(Synchronous()).Drain(context.Background()); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("got %v, want %v", err, ErrNotStarted)
+	}
+}
+
+func TestMemoryWorkers(t *testing.T) {
+	m := NewMemory(WithConcurrency(2))
+	done := make(chan struct{})
+
+	if err := m.Start(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		close(done)
+
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Shutdown()
+
+	if _, err := m.Enqueue(context.Background(), asynq.NewTask("test", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not processed by a worker")
+	}
+}
+
+func TestMemoryUniqueArchived(t *testing.T) {
+	m := NewMemory(Synchronous())
+	ctx := context.Background()
+
+	if err := m.Start(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		return fmt.Errorf("bad payload: %w", asynq.SkipRetry)
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Shutdown()
+
+	enqueue := func() error {
+		_, err := m.Enqueue(ctx, asynq.NewTask("test", []byte("a")), asynq.Unique(time.Minute))
+
+		return err
+	}
+
+	if err := enqueue(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like asynq, the lock of an archived task is only released by its TTL
+	if err := enqueue(); !errors.Is(err, asynq.ErrDuplicateTask) {
+		t.Errorf("after archiving: got %v, want %v", err, asynq.ErrDuplicateTask)
+	}
+
+	m.Advance(2 * time.Minute)
+
+	if err := enqueue(); err != nil {
+		t.Errorf("after the TTL: %v", err)
+	}
+}
+
+func TestMemoryAfterShutdown(t *testing.T) {
+	m := NewMemory(Synchronous())
+
+	m.Shutdown()
+
+	if _, err := m.Enqueue(context.Background(), asynq.NewTask("test", nil)); !errors.Is(err, ErrShutdown) {
+		t.Errorf("got %v, want %v", err, ErrShutdown)
+	}
+}
@@ -0,0 +1,179 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	ctxlog "github.com/khvh/gwf/pkg/logger"
+)
+
+// envelopeField is the reserved payload field carrying the trace context and request ID of the producer
+const envelopeField = "_gwf"
+
+type envelope struct {
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+}
+
+var tracer = otel.Tracer("github.com/khvh/gwf/pkg/queue")
+
+// NewTaskContext creates a task like asynq.NewTask, JSON object payloads also carry the trace context and request ID of ctx.
+// Handlers read the payload without them with Payload.
+func NewTaskContext(ctx context.Context, typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	return asynq.NewTask(typename, inject(ctx, payload), opts...)
+}
+
+// withEnvelope returns the task with the envelope of ctx when its payload has none yet.
+// The task is created again then, options given to asynq.NewTask are left out.
+func withEnvelope(ctx context.Context, task *asynq.Task) *asynq.Task {
+	if bytes.HasPrefix(task.Payload(), []byte(`{"`+envelopeField+`":`)) {
+		return task
+	}
+
+	payload := inject(ctx, task.Payload())
+	if len(payload) == len(task.Payload()) {
+		return task
+	}
+
+	return asynq.NewTask(task.Type(), payload)
+}
+
+// inject adds the envelope to a JSON object payload, other payloads are returned unchanged
+func inject(ctx context.Context, payload []byte) []byte {
+	carrier := propagation.MapCarrier{}
+
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	env := envelope{
+		TraceParent: carrier.Get("traceparent"),
+		TraceState:  carrier.Get("tracestate"),
+		RequestID:   ctxlog.RequestID(ctx),
+	}
+
+	if env == (envelope{}) {
+		return payload
+	}
+
+	trimmed := bytes.TrimSpace(payload)
+
+	if len(trimmed) < 2 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return payload
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		return payload
+	}
+
+	field := append([]byte(`{"`+envelopeField+`":`), b...)
+
+	if bytes.Equal(bytes.TrimSpace(trimmed[1:]), []byte("}")) {
+		return append(field, '}')
+	}
+
+	return append(append(field, ','), trimmed[1:]...)
+}
+
+// extract reads the envelope from a payload
+func extract(payload []byte) envelope {
+	var wrapper struct {
+		Envelope envelope `json:"_gwf"`
+	}
+
+	if len(payload) == 0 || payload[0] != '{' {
+		return envelope{}
+	}
+
+	_ = json.Unmarshal(payload, &wrapper)
+
+	return wrapper.Envelope
+}
+
+// Payload returns the payload of a task without the trace envelope, use it instead of task.Payload in handlers
+// added with AddHandler or AddHandlerFunc, handlers of a Task receive the decoded payload without it
+func Payload(task *asynq.Task) []byte {
+	return stripEnvelope(task.Payload())
+}
+
+// stripEnvelope returns the payload without the envelope added by inject
+func stripEnvelope(payload []byte) []byte {
+	prefix := []byte(`{"` + envelopeField + `":`)
+
+	if !bytes.HasPrefix(payload, prefix) {
+		return payload
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload[len(prefix):]))
+
+	var env json.RawMessage
+
+	if err := dec.Decode(&env); err != nil {
+		return payload
+	}
+
+	rest := bytes.TrimSpace(payload[len(prefix)+int(dec.InputOffset()):])
+
+	if len(rest) == 0 {
+		return payload
+	}
+
+	if rest[0] == '}' {
+		return []byte("{}")
+	}
+
+	return append([]byte{'{'}, rest[1:]...)
+}
+
+// propagate continues the producer's trace in a consumer span and attaches task and request IDs to the context logger
+func propagate(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		env := extract(t.Payload())
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+			"traceparent": env.TraceParent,
+			"tracestate":  env.TraceState,
+		})
+
+		id, _ := TaskID(ctx)
+
+		ctx, span := tracer.Start(ctx, t.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "asynq"),
+				attribute.String("messaging.operation", "process"),
+				attribute.String("messaging.message.id", id),
+			),
+		)
+		defer span.End()
+
+		l := ctxlog.Ctx(ctx).With().Str("task", t.Type()).Str("task_id", id)
+
+		if span.SpanContext().HasTraceID() {
+			l = l.Str("trace_id", span.SpanContext().TraceID().String())
+		}
+
+		ll := l.Logger()
+		ctx = ll.WithContext(ctx)
+
+		if env.RequestID != "" {
+			ctx = ctxlog.WithRequestID(ctx, env.RequestID)
+		}
+
+		err := next.ProcessTask(ctx, t)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	})
+}
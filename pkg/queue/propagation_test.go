@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	ctxlog "github.com/khvh/gwf/pkg/logger"
+)
+
+type uniquePayload struct {
+	UserID int `json:"userId"`
+}
+
+func TestUniqueAcrossRequests(t *testing.T) {
+	q := New(NewMemory(Synchronous()))
+	task := NewTask[uniquePayload]("unique:test", asynq.Unique(time.Minute))
+
+	first, err := task.Enqueue(ctxlog.WithRequestID(context.Background(), "request-1"), uniquePayload{UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = task.Enqueue(ctxlog.WithRequestID(context.Background(), "request-2"), uniquePayload{UserID: 1})
+	if !errors.Is(err, asynq.ErrDuplicateTask) {
+		t.Fatalf("got %v, want %v", err, asynq.ErrDuplicateTask)
+	}
+
+	if _, err := task.Enqueue(ctxlog.WithRequestID(context.Background(), "request-3"), uniquePayload{UserID: 2}); err != nil {
+		t.Errorf("another payload is not a duplicate: %v", err)
+	}
+
+	if !bytes.Contains(first.Payload, []byte("request-1")) {
+		t.Errorf("payload %s does not carry the request ID", first.Payload)
+	}
+
+	q.Shutdown()
+}
+
+func TestStripEnvelope(t *testing.T) {
+	ctx := ctxlog.WithRequestID(context.Background(), `a"},{"b`)
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"object", `{"userId":1,"name":"x"}`},
+		{"empty object", `{}`},
+		{"nested", `{"a":{"b":[1,2,{"c":"}"}]}}`},
+		{"array", `[1,2]`},
+		{"string", `"text"`},
+		{"binary", "\x00\x01"},
+		{"empty", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injected := inject(ctx, []byte(tt.payload))
+
+			if got := string(stripEnvelope(injected)); got != tt.payload {
+				t.Errorf("stripEnvelope(%s) = %s, want %s", injected, got, tt.payload)
+			}
+
+			if uniqueKey("default", "t", injected) != uniqueKey("default", "t", []byte(tt.payload)) {
+				t.Error("the envelope changes the unique key")
+			}
+		})
+	}
+}
+
+func TestHandlersWithoutEnvelope(t *testing.T) {
+	m := NewMemory(Synchronous())
+	task := NewTask[map[string]any]("envelope:typed")
+
+	var typed map[string]any
+	var raw []byte
+
+	q := New(m).
+		Register(task.Handle(func(_ context.Context, payload map[string]any) error {
+			typed = payload
+
+			return nil
+		})).
+		AddHandlerFunc("envelope:raw", func(_ context.Context, t *asynq.Task) error {
+			raw = Payload(t)
+
+			return nil
+		})
+
+	q.Run()
+	defer q.Shutdown()
+
+	ctx := ctxlog.WithRequestID(context.Background(), "request-1")
+
+	if _, err := task.Enqueue(ctx, map[string]any{"userId": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.Client().Enqueue(ctx, NewTaskContext(ctx, "envelope:raw", []byte(`{"userId":1}`))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := typed[envelopeField]; ok || len(typed) != 1 {
+		t.Errorf("typed handler got %v, want only userId", typed)
+	}
+
+	if string(raw) != `{"userId":1}` {
+		t.Errorf("raw handler got %s, want {\"userId\":1}", raw)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog/log"
 )
 
@@ -65,8 +66,13 @@ type Queues map[string]int
 
 // Queue holds Asynq server things
 type Queue struct {
-	srv *asynq.Server
-	mux *asynq.ServeMux
+	backend   Backend
+	client    *Client
+	mux       *asynq.ServeMux
+	cron      *cron.Cron
+	policies  *policies
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 var queueInstance *Queue
@@ -87,23 +93,62 @@ func metrics(next asynq.Handler) asynq.Handler {
 // CreateServer creates a new Asynq server
 func CreateServer(redisAddress string, concurrency int, qs Queues) *Queue {
 	if queueInstance == nil {
-		srv := asynq.NewServer(
-			asynq.RedisClientOpt{Addr: redisAddress},
-			asynq.Config{
-				Concurrency: concurrency,
-				Queues:      qs,
-				Logger:      logger{},
-			},
-		)
-
-		mux := asynq.NewServeMux()
-
-		queueInstance = &Queue{srv, mux}
+		queueInstance = New(NewRedis(asynq.RedisClientOpt{Addr: redisAddress}, concurrency, qs))
 	}
 
 	return queueInstance
 }
 
+// New creates a queue processing tasks of the backend, its client replaces the default client used by Task.Enqueue
+func New(backend Backend) *Queue {
+	mux := asynq.NewServeMux()
+	pol := newPolicies()
+
+	mux.Use(propagate, results, pol.handle)
+
+	if rd, ok := backend.(retryDelayer); ok {
+		rd.wrapRetryDelay(pol.retryDelay)
+	}
+
+	client := newClient(backend)
+
+	SetDefaultClient(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		backend:  backend,
+		client:   client,
+		mux:      mux,
+		policies: pol,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Policy sets how failures of a task type are handled: the retry backoff, the error classifier and callbacks.
+// An empty typename sets the default policy, options of a type override it and callbacks of both are called.
+func (q *Queue) Policy(typename string, opts ...PolicyOption) *Queue {
+	q.policies.set(typename, opts)
+
+	return q
+}
+
+// Backend returns the backend of the queue, e.g. to Drain a Memory backend in tests
+func (q *Queue) Backend() Backend {
+	return q.backend
+}
+
+// Context returns a context that is cancelled when the queue shuts down, e.g. to stop goroutines enqueueing tasks
+func (q *Queue) Context() context.Context {
+	return q.ctx
+}
+
+// Client returns the client enqueueing to the backend of the queue
+func (q *Queue) Client() *Client {
+	return q.client
+}
+
 // MountMonitor mounts asynqmon
 func (q *Queue) MountMonitor(redisAddress, redisPw string, app *echo.Echo) *Queue {
 	mon := asynqmon.New(asynqmon.Options{
@@ -146,40 +191,33 @@ func (q *Queue) AddHandler(pattern string, handler asynq.Handler) *Queue {
 	return q
 }
 
-func (q *Queue) Run() {
-	go func() {
-		if err := q.srv.Run(q.mux); err != nil {
-			log.Fatal().Err(err)
-		}
-	}()
-}
+// Register adds handlers created with Task.Handle to mux
+func (q *Queue) Register(handlers ...*Handler) *Queue {
+	for _, h := range handlers {
+		q.mux.Handle(h.Pattern, h.FN)
+	}
 
-// Client ...
-type Client struct {
-	client *asynq.Client
+	return q
 }
 
-var clientInstance *Client
-
-// NewClient ...
-func NewClient(redisAddress string) *Client {
-	if clientInstance == nil {
-		clientInstance = &Client{
-			client: asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddress}),
-		}
+// Run starts processing tasks and the schedules in the background
+func (q *Queue) Run() {
+	if err := q.backend.Start(q.mux); err != nil {
+		log.Fatal().Err(err).Send()
 	}
 
-	return clientInstance
+	if q.cron != nil {
+		q.cron.Start()
+	}
 }
 
-// Add a new task to queue
-func (c *Client) Add(task *asynq.Task, opts ...asynq.Option) *Client {
-	info, err := c.client.Enqueue(task, opts...)
-	if err != nil {
-		log.Err(err).Send()
-	}
+// Shutdown cancels the context of the queue, stops the schedules and waits for active tasks
+func (q *Queue) Shutdown() {
+	q.cancel()
 
-	log.Trace().Msgf("Added task [%s] to [%s]", info.ID, info.Queue)
+	if q.cron != nil {
+		<-q.cron.Stop().Done()
+	}
 
-	return c
+	q.backend.Shutdown()
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"testing"
+)
+
+func TestQueueContext(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	q := New(NewMemory(Synchronous()))
+
+	q.Run()
+
+	if err := q.Context().Err(); err != nil {
+		t.Fatalf("got %v before shutdown, want nil", err)
+	}
+
+	q.Shutdown()
+
+	select {
+	case <-q.Context().Done():
+	default:
+		t.Error("the context is not done after shutdown")
+	}
+}
@@ -0,0 +1,254 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+// unlockScript releases a unique lock held by the task given as argument
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Redis is the asynq backend, the client and server connect on first use
+type Redis struct {
+	opt    asynq.RedisClientOpt
+	config asynq.Config
+	mu     sync.Mutex
+	client *asynq.Client
+	srv    *asynq.Server
+	insp   *asynq.Inspector
+	rdb    redis.UniversalClient
+	closed bool
+}
+
+// NewRedis creates a Redis backend processing the queues with concurrency workers
+func NewRedis(opt asynq.RedisClientOpt, concurrency int, qs Queues) *Redis {
+	return &Redis{
+		opt: opt,
+		config: asynq.Config{
+			Concurrency: concurrency,
+			Queues:      qs,
+			Logger:      logger{},
+		},
+	}
+}
+
+// Conn returns the Redis connection options, e.g. for an asynq.Inspector
+func (r *Redis) Conn() asynq.RedisClientOpt {
+	return r.opt
+}
+
+// Enqueue adds a task to Redis
+func (r *Redis) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error) {
+	client, err := r.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	// asynq derives the unique key from the whole payload, including the envelope which differs per request.
+	// Every unique task takes the lock of uniqueKey instead, with or without an envelope, so they see each other.
+	if queue, ttl := uniqueOptions(opts); ttl > 0 {
+		return r.enqueueUnique(ctx, client, task, uniqueKey(queue, task.Type(), task.Payload()), ttl, opts)
+	}
+
+	return client.EnqueueContext(ctx, task, opts...)
+}
+
+// conn returns the client, it is created on first use
+func (r *Redis) conn() (*asynq.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrShutdown
+	}
+
+	if r.client == nil {
+		r.client = asynq.NewClient(r.opt)
+	}
+
+	return r.client, nil
+}
+
+// enqueueUnique locks the unique key of the task for ttl, the lock is released once the task is processed successfully
+func (r *Redis) enqueueUnique(ctx context.Context, client *asynq.Client, task *asynq.Task, key string, ttl time.Duration, opts []asynq.Option) (*TaskInfo, error) {
+	id := uuid.NewString()
+
+	for _, opt := range opts {
+		if opt.Type() == asynq.TaskIDOpt {
+			id = opt.Value().(string)
+		}
+	}
+
+	rdb, err := r.redis()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := rdb.SetNX(ctx, key, id, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, asynq.ErrDuplicateTask
+	}
+
+	rest := make([]asynq.Option, 0, len(opts)+1)
+
+	for _, opt := range opts {
+		if opt.Type() != asynq.UniqueOpt {
+			rest = append(rest, opt)
+		}
+	}
+
+	info, err := client.EnqueueContext(ctx, task, append(rest, asynq.TaskID(id))...)
+	if err != nil {
+		rdb.Del(ctx, key)
+
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// unlock releases the unique lock taken by enqueueUnique, the lock is only released when the task holds it
+func (r *Redis) unlock(ctx context.Context, task *asynq.Task) {
+	id, _ := asynq.GetTaskID(ctx)
+	queue, _ := asynq.GetQueueName(ctx)
+
+	rdb, err := r.redis()
+	if err != nil {
+		log.Trace().Err(err).Str("task", task.Type()).Msg("While releasing the unique lock")
+
+		return
+	}
+
+	if err := unlockScript.Run(ctx, rdb, []string{uniqueKey(queue, task.Type(), task.Payload())}, id).Err(); err != nil {
+		log.Trace().Err(err).Str("task", task.Type()).Msg("While releasing the unique lock")
+	}
+}
+
+// redis returns the Redis client for the unique locks, it is created on first use
+func (r *Redis) redis() (redis.UniversalClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrShutdown
+	}
+
+	if r.rdb == nil {
+		r.rdb = r.opt.MakeRedisClient().(redis.UniversalClient)
+	}
+
+	return r.rdb, nil
+}
+
+// Task looks the task up in every queue
+func (r *Redis) Task(_ context.Context, id string) (*TaskInfo, error) {
+	insp, err := r.inspector()
+	if err != nil {
+		return nil, err
+	}
+
+	queues, err := insp.Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range queues {
+		info, err := insp.GetTaskInfo(q, id)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+
+		return info, err
+	}
+
+	return nil, asynq.ErrTaskNotFound
+}
+
+// inspector returns the inspector, it is created on first use
+func (r *Redis) inspector() (*asynq.Inspector, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrShutdown
+	}
+
+	if r.insp == nil {
+		r.insp = asynq.NewInspector(r.opt)
+	}
+
+	return r.insp, nil
+}
+
+func (r *Redis) wrapRetryDelay(wrap func(asynq.RetryDelayFunc) asynq.RetryDelayFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.config.RetryDelayFunc = wrap(r.config.RetryDelayFunc)
+}
+
+// Start starts an asynq server
+func (r *Redis) Start(handler asynq.Handler) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.srv = asynq.NewServer(r.opt, r.config)
+
+	return r.srv.Start(asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		err := handler.ProcessTask(ctx, t)
+		if err == nil {
+			r.unlock(ctx, t)
+		}
+
+		return err
+	}))
+}
+
+// Shutdown stops the server and closes the client, enqueueing afterwards returns ErrShutdown
+func (r *Redis) Shutdown() {
+	r.mu.Lock()
+	srv := r.srv
+	r.srv = nil
+	r.mu.Unlock()
+
+	// Active tasks may still release their unique locks with the Redis client, r.mu is not held while waiting for them
+	if srv != nil {
+		srv.Shutdown()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	if r.client != nil {
+		_ = r.client.Close()
+		r.client = nil
+	}
+
+	if r.insp != nil {
+		_ = r.insp.Close()
+		r.insp = nil
+	}
+
+	if r.rdb != nil {
+		_ = r.rdb.Close()
+		r.rdb = nil
+	}
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRedisAfterShutdown(t *testing.T) {
+	r := NewRedis(asynq.RedisClientOpt{Addr: "localhost:0"}, 1, nil)
+	ctx := context.Background()
+
+	r.Shutdown()
+
+	if _, err := r.Enqueue(ctx, asynq.NewTask("test", nil)); !errors.Is(err, ErrShutdown) {
+		t.Errorf("enqueue: got %v, want %v", err, ErrShutdown)
+	}
+
+	if _, err := r.Enqueue(ctx, NewTaskContext(ctx, "test", []byte(`{}`)), asynq.Unique(time.Minute)); !errors.Is(err, ErrShutdown) {
+		t.Errorf("unique enqueue: got %v, want %v", err, ErrShutdown)
+	}
+
+	if _, err := r.Task(ctx, "id"); !errors.Is(err, ErrShutdown) {
+		t.Errorf("task: got %v, want %v", err, ErrShutdown)
+	}
+
+	// A task finishing during Shutdown releases its lock without recreating the Redis client
+	r.unlock(ctx, asynq.NewTask("test", nil))
+
+	if _, err := r.redis(); !errors.Is(err, ErrShutdown) || r.rdb != nil {
+		t.Errorf("redis: got %v, want %v without a client", err, ErrShutdown)
+	}
+}
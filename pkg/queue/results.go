@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrNoResultWriter is returned by WriteResult outside of a task handler
+var ErrNoResultWriter = errors.New("no task is processed with the context")
+
+type resultKey struct{}
+
+type resultWriter func(b []byte) error
+
+func withResultWriter(ctx context.Context, w resultWriter) context.Context {
+	return context.WithValue(ctx, resultKey{}, w)
+}
+
+// results passes the result writer of asynq tasks to WriteResult, other backends put theirs in the context
+func results(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if _, ok := ctx.Value(resultKey{}).(resultWriter); !ok && t.ResultWriter() != nil {
+			ctx = withResultWriter(ctx, func(b []byte) error {
+				_, err := t.ResultWriter().Write(b)
+
+				return err
+			})
+		}
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// WriteResult stores v as JSON as the result of the task processed with ctx.
+// Results are kept as long as the task, enqueue it with asynq.Retention to query them after it completed.
+func WriteResult(ctx context.Context, v any) error {
+	w, ok := ctx.Value(resultKey{}).(resultWriter)
+	if !ok {
+		return ErrNoResultWriter
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return w(b)
+}
+
+// HandleResult creates a handler for the task storing the value fn returns as the result, see WriteResult
+func HandleResult[P, R any](t *Task[P], fn func(ctx context.Context, payload P) (R, error)) *Handler {
+	return t.Handle(func(ctx context.Context, payload P) error {
+		res, err := fn(ctx, payload)
+		if err != nil {
+			return err
+		}
+
+		return WriteResult(ctx, res)
+	})
+}
+
+// Inspect returns a task enqueued with the default client, see Client.Inspect
+func Inspect(ctx context.Context, id string) (*TaskInfo, error) {
+	c := DefaultClient()
+	if c == nil {
+		return nil, ErrNoClient
+	}
+
+	return c.Inspect(ctx, id)
+}
+
+// TaskStatus is the state of a task and its result once it completed
+type TaskStatus[R any] struct {
+	ID            string     `json:"id"`
+	Type          string     `json:"type"`
+	Queue         string     `json:"queue"`
+	State         string     `json:"state" enum:"active,pending,scheduled,retry,archived,completed,aggregating"`
+	Retried       int        `json:"retried"`
+	MaxRetry      int        `json:"maxRetry"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextProcessAt *time.Time `json:"nextProcessAt,omitempty"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	Result        *R         `json:"result,omitempty"`
+}
+
+// Status returns the state of a task with its result decoded as R
+func Status[R any](info *TaskInfo) (*TaskStatus[R], error) {
+	s := &TaskStatus[R]{
+		ID:        info.ID,
+		Type:      info.Type,
+		Queue:     info.Queue,
+		State:     info.State.String(),
+		Retried:   info.Retried,
+		MaxRetry:  info.MaxRetry,
+		LastError: info.LastErr,
+	}
+
+	if !info.NextProcessAt.IsZero() {
+		s.NextProcessAt = &info.NextProcessAt
+	}
+
+	if !info.CompletedAt.IsZero() {
+		s.CompletedAt = &info.CompletedAt
+	}
+
+	if len(info.Result) > 0 {
+		var res R
+
+		if err := json.Unmarshal(info.Result, &res); err != nil {
+			return nil, fmt.Errorf("decoding the result of %s: %w", info.ID, err)
+		}
+
+		s.Result = &res
+	}
+
+	return s, nil
+}
@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	scheduledCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduled_tasks_enqueued_total",
+			Help: "The total number of periodic task runs enqueued",
+		},
+		[]string{"task_type"},
+	)
+
+	skippedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduled_tasks_skipped_total",
+			Help: "The total number of periodic task runs skipped because another instance enqueued them",
+		},
+		[]string{"task_type"},
+	)
+
+	missedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduled_tasks_missed_total",
+			Help: "The total number of periodic task runs that could not be enqueued",
+		},
+		[]string{"task_type"},
+	)
+)
+
+// Cron enqueues the task on a cron schedule, e.g. "0 * * * *", "@daily" or "@every 1h".
+// Runs of "@every" are aligned to multiples of the interval, so that every instance runs at the same times.
+// Every instance may register the same schedule, a run is enqueued once as its task ID is derived from the type and the time of the run.
+// A completed run is kept at least until the next one, so an instance firing late still sees it.
+// Runs get the defaults of the task type set with Client.Defaults, a task ID given in opts is replaced.
+func (q *Queue) Cron(cronspec string, task *asynq.Task, opts ...asynq.Option) *Queue {
+	schedule, err := cron.ParseStandard(cronspec)
+	if err != nil {
+		log.Fatal().Err(err).Str("cron", cronspec).Str("task", task.Type()).Send()
+	}
+
+	if d, ok := schedule.(cron.ConstantDelaySchedule); ok {
+		schedule = interval(d.Delay)
+	}
+
+	q.cronInstance().Schedule(schedule, cron.FuncJob(func() {
+		at := previous(schedule, time.Now())
+
+		opts := append(append([]asynq.Option{}, opts...), asynq.TaskID(fmt.Sprintf("%s:%d", task.Type(), at.Unix())))
+
+		if next := schedule.Next(at).Sub(at); retention(q.client.options(task.Type(), opts)) < next {
+			opts = append(opts, asynq.Retention(next))
+		}
+
+		info, err := q.client.Enqueue(context.Background(), task, opts...)
+		if err != nil {
+			scheduleFailed(task, err)
+
+			return
+		}
+
+		scheduled(info)
+	}))
+
+	log.Trace().Msgf("Scheduled task [%s] with [%s]", task.Type(), cronspec)
+
+	return q
+}
+
+// Every enqueues the task at a fixed interval
+func (q *Queue) Every(interval time.Duration, task *asynq.Task, opts ...asynq.Option) *Queue {
+	return q.Cron("@every "+interval.String(), task, opts...)
+}
+
+// interval runs at the multiples of a duration
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(i)).Add(time.Duration(i))
+}
+
+// previous returns the last run of the schedule at or before now, the run a job firing now belongs to
+func previous(schedule cron.Schedule, now time.Time) time.Time {
+	// Look back further until a run is found, a year of the longest schedules is covered
+	for back := time.Minute; back < 2*366*24*time.Hour; back *= 2 {
+		run := schedule.Next(now.Add(-back))
+		if run.IsZero() || run.After(now) {
+			continue
+		}
+
+		for next := schedule.Next(run); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+			run = next
+		}
+
+		return run
+	}
+
+	return now
+}
+
+// retention returns the retention opts end up with
+func retention(opts []asynq.Option) time.Duration {
+	var d time.Duration
+
+	for _, opt := range opts {
+		if opt.Type() == asynq.RetentionOpt {
+			d = opt.Value().(time.Duration)
+		}
+	}
+
+	return d
+}
+
+// cronInstance runs the schedules in process
+func (q *Queue) cronInstance() *cron.Cron {
+	if q.cron == nil {
+		q.cron = cron.New()
+	}
+
+	return q.cron
+}
+
+func scheduled(info *TaskInfo) {
+	scheduledCounter.WithLabelValues(info.Type).Inc()
+}
+
+func scheduleFailed(task *asynq.Task, err error) {
+	if errors.Is(err, asynq.ErrDuplicateTask) || errors.Is(err, asynq.ErrTaskIDConflict) {
+		skippedCounter.WithLabelValues(task.Type()).Inc()
+
+		return
+	}
+
+	missedCounter.WithLabelValues(task.Type()).Inc()
+
+	log.Error().Err(err).Str("task", task.Type()).Msg("While enqueueing a scheduled task")
+}
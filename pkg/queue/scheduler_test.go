@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robfig/cron/v3"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// unavailable is a backend that cannot enqueue
+type unavailable struct {
+	*Memory
+}
+
+func (unavailable) Enqueue(context.Context, *asynq.Task, ...asynq.Option) (*TaskInfo, error) {
+	return nil, errUnavailable
+}
+
+// fire runs the schedules of the queue once
+func fire(q *Queue) {
+	for _, entry := range q.cron.Entries() {
+		entry.Job.Run()
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	tests := []struct {
+		name     string
+		schedule func(q *Queue, task *asynq.Task)
+	}{
+		{"cron", func(q *Queue, task *asynq.Task) { q.Cron("@hourly", task) }},
+		{"every", func(q *Queue, task *asynq.Task) { q.Every(time.Hour, task) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typename := "scheduled:" + tt.name
+			m := NewMemory(Synchronous())
+
+			scheduled := testutil.ToFloat64(scheduledCounter.WithLabelValues(typename))
+			skipped := testutil.ToFloat64(skippedCounter.WithLabelValues(typename))
+			missed := testutil.ToFloat64(missedCounter.WithLabelValues(typename))
+
+			// Every instance schedules the task, an unavailable one misses the run
+			instances := []*Queue{New(m), New(m), New(unavailable{NewMemory(Synchronous())})}
+
+			for _, q := range instances {
+				q.Client().Defaults(typename, asynq.Queue("critical"), asynq.MaxRetry(2))
+				tt.schedule(q, asynq.NewTask(typename, nil))
+				fire(q)
+			}
+
+			tasks := m.Tasks()
+			if len(tasks) != 1 {
+				t.Fatalf("enqueued %d runs, want 1", len(tasks))
+			}
+
+			if tasks[0].Queue != "critical" || tasks[0].MaxRetry != 2 {
+				t.Errorf("run is in queue %s with %d retries, want the defaults of the type", tasks[0].Queue, tasks[0].MaxRetry)
+			}
+
+			for name, c := range map[string]struct{ before, after float64 }{
+				"scheduled": {scheduled, testutil.ToFloat64(scheduledCounter.WithLabelValues(typename))},
+				"skipped":   {skipped, testutil.ToFloat64(skippedCounter.WithLabelValues(typename))},
+				"missed":    {missed, testutil.ToFloat64(missedCounter.WithLabelValues(typename))},
+			} {
+				if c.after-c.before != 1 {
+					t.Errorf("%s %v runs, want 1", name, c.after-c.before)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleFires(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	m := NewMemory(Synchronous())
+	q := New(m).Every(time.Second, asynq.NewTask("scheduled:fires", nil))
+
+	q.Run()
+	defer q.Shutdown()
+
+	for deadline := time.Now().Add(3 * time.Second); len(m.Tasks()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the schedule did not fire")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	weekdays, err := cron.ParseStandard("0 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-15 is a Thursday
+	thursday := time.Date(2026, 10, 15, 9, 0, 0, 0, time.Local)
+	hour := thursday.Truncate(time.Hour)
+
+	tests := []struct {
+		name     string
+		schedule cron.Schedule
+		now      time.Time
+		want     time.Time
+	}{
+		{"at the run", weekdays, thursday.Add(time.Millisecond), thursday},
+		{"late", weekdays, thursday.Add(5 * time.Minute), thursday},
+		{"over the weekend", weekdays, thursday.AddDate(0, 0, 3), thursday.AddDate(0, 0, 1)},
+		{"interval", interval(time.Hour), hour.Add(90 * time.Minute), hour.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previous(tt.schedule, tt.now); !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleRunOnce(t *testing.T) {
+	defer SetDefaultClient(nil)
+
+	tests := []struct {
+		name string
+		opts []asynq.Option
+	}{
+		{"default retention", nil},
+		{"shorter retention", []asynq.Option{asynq.Retention(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typename := "scheduled:once:" + tt.name
+			m := NewMemory(Synchronous())
+
+			if err := m.Start(asynq.HandlerFunc(func(context.Context, *asynq.Task) error { return nil })); err != nil {
+				t.Fatal(err)
+			}
+
+			// The run completes before the second instance fires
+			first, second := New(m), New(m)
+
+			first.Every(24*time.Hour, asynq.NewTask(typename, nil), tt.opts...)
+			second.Every(24*time.Hour, asynq.NewTask(typename, nil), tt.opts...)
+
+			fire(first)
+
+			if err := m.Drain(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			m.Advance(time.Hour)
+			fire(second)
+
+			tasks := m.Tasks()
+			if len(tasks) != 1 {
+				t.Fatalf("enqueued %d runs, want 1", len(tasks))
+			}
+
+			if tasks[0].State != asynq.TaskStateCompleted || tasks[0].Retention != 24*time.Hour {
+				t.Errorf("run is %s with retention %s, want it completed and kept until the next run", tasks[0].State, tasks[0].Retention)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrNoClient is returned when a task is enqueued before a client was created with NewClient
+var ErrNoClient = errors.New("queue client is not created, call queue.New or queue.NewClient first")
+
+// Enqueuer adds tasks, implemented by Client
+type Enqueuer interface {
+	Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error)
+}
+
+// Validator is implemented by payloads that check themselves before they are enqueued and after they are decoded
+type Validator interface {
+	Validate() error
+}
+
+// Task is a typed task definition, it (de)serializes payloads of type P as JSON
+type Task[P any] struct {
+	name string
+	opts []asynq.Option
+}
+
+// NewTask defines a task with a name and default options, which can be overridden when enqueueing
+func NewTask[P any](name string, opts ...asynq.Option) *Task[P] {
+	return &Task[P]{
+		name: name,
+		opts: opts,
+	}
+}
+
+// Name returns the task type name
+func (t *Task[P]) Name() string {
+	return t.name
+}
+
+// New validates and serializes the payload into an asynq.Task.
+// Backends cannot read options from an asynq.Task, give Options when enqueueing or scheduling it.
+func (t *Task[P]) New(payload P) (*asynq.Task, error) {
+	return t.NewContext(context.Background(), payload)
+}
+
+// NewContext is like New, the task also carries the trace context and request ID of ctx to the handler
+func (t *Task[P]) NewContext(ctx context.Context, payload P) (*asynq.Task, error) {
+	b, err := t.encode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTaskContext(ctx, t.name, b), nil
+}
+
+// Options returns the default options of the task followed by opts, e.g. for Queue.Cron with a task created by New
+func (t *Task[P]) Options(opts ...asynq.Option) []asynq.Option {
+	return t.options(opts)
+}
+
+// Enqueue adds a task with the payload to the queue using the default client, see SetDefaultClient
+func (t *Task[P]) Enqueue(ctx context.Context, payload P, opts ...asynq.Option) (*TaskInfo, error) {
+	c := DefaultClient()
+	if c == nil {
+		return nil, ErrNoClient
+	}
+
+	return t.EnqueueTo(ctx, c, payload, opts...)
+}
+
+// EnqueueTo adds a task with the payload using e, e.g. a Client or an outbox transaction
+func (t *Task[P]) EnqueueTo(ctx context.Context, e Enqueuer, payload P, opts ...asynq.Option) (*TaskInfo, error) {
+	b, err := t.encode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Options are given when enqueueing as backends cannot read the options of an asynq.Task
+	return e.Enqueue(ctx, NewTaskContext(ctx, t.name, b), t.options(opts)...)
+}
+
+// EnqueueTx adds a task with the payload to tx, it is enqueued once the transaction commits
+func (t *Task[P]) EnqueueTx(ctx context.Context, tx *Tx, payload P, opts ...asynq.Option) error {
+	b, err := t.encode(payload)
+	if err != nil {
+		return err
+	}
+
+	tx.Enqueue(NewTaskContext(ctx, t.name, b), t.options(opts)...)
+
+	return nil
+}
+
+// Validate checks the payload when P or *P implements Validator, it is called by Enqueue and Decode
+func (t *Task[P]) Validate(payload P) error {
+	return validate(payload)
+}
+
+func (t *Task[P]) encode(payload P) ([]byte, error) {
+	if err := validate(payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", t.name, err)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s payload: %w", t.name, err)
+	}
+
+	return b, nil
+}
+
+// options returns the default options followed by opts, later options win
+func (t *Task[P]) options(opts []asynq.Option) []asynq.Option {
+	return append(append([]asynq.Option{}, t.opts...), opts...)
+}
+
+// Decode deserializes and validates the payload of a task, errors wrap asynq.SkipRetry as retrying cannot fix them
+func (t *Task[P]) Decode(task *asynq.Task) (P, error) {
+	var payload P
+
+	if err := json.Unmarshal(Payload(task), &payload); err != nil {
+		return payload, fmt.Errorf("decoding %s payload: %v: %w", t.name, err, asynq.SkipRetry)
+	}
+
+	if err := validate(payload); err != nil {
+		return payload, fmt.Errorf("invalid %s payload: %v: %w", t.name, err, asynq.SkipRetry)
+	}
+
+	return payload, nil
+}
+
+// Handle creates a handler for the task that receives the decoded payload, register it with Queue.Register
+func (t *Task[P]) Handle(fn func(ctx context.Context, payload P) error) *Handler {
+	return &Handler{
+		Pattern: t.name,
+		FN: asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			payload, err := t.Decode(task)
+			if err != nil {
+				return err
+			}
+
+			return fn(ctx, payload)
+		}),
+	}
+}
+
+// validate calls Validate when P or *P implements Validator
+func validate[P any](payload P) error {
+	if v, ok := any(payload).(Validator); ok {
+		return v.Validate()
+	}
+
+	if v, ok := any(&payload).(Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func (o order) Validate() error {
+	if o.ID == "" {
+		return errors.New("id is required")
+	}
+
+	return nil
+}
+
+type refund struct {
+	Amount int `json:"amount"`
+}
+
+func (r *refund) Validate() error {
+	if r.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	return nil
+}
+
+// recorder counts the tasks it is asked to enqueue
+type recorder struct {
+	tasks int
+}
+
+func (r *recorder) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*TaskInfo, error) {
+	r.tasks++
+
+	return &TaskInfo{}, nil
+}
+
+func TestValidateOnEnqueue(t *testing.T) {
+	orders := NewTask[order]("test:order")
+	refunds := NewTask[refund]("test:refund")
+
+	tests := []struct {
+		name    string
+		enqueue func(ctx context.Context, e Enqueuer) (*TaskInfo, error)
+		valid   bool
+	}{
+		{"valid value receiver", enqueueTo(orders, order{ID: "1"}), true},
+		{"invalid value receiver", enqueueTo(orders, order{}), false},
+		{"valid pointer receiver", enqueueTo(refunds, refund{Amount: 1}), true},
+		{"invalid pointer receiver", enqueueTo(refunds, refund{}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+
+			_, err := tt.enqueue(context.Background(), r)
+
+			if tt.valid {
+				if err != nil || r.tasks != 1 {
+					t.Errorf("got %v with %d tasks enqueued, want the task enqueued", err, r.tasks)
+				}
+
+				return
+			}
+
+			if err == nil || r.tasks != 0 {
+				t.Errorf("got %v with %d tasks enqueued, want a validation error", err, r.tasks)
+			}
+		})
+	}
+
+	if _, err := orders.New(order{}); err == nil {
+		t.Error("New accepted an invalid payload")
+	}
+
+	if err := orders.EnqueueTx(context.Background(), &Tx{}, order{}); err == nil {
+		t.Error("EnqueueTx accepted an invalid payload")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	resize := NewTask[order]("test:resize", asynq.Retention(time.Hour), asynq.MaxRetry(3))
+
+	task, err := resize.New(order{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := newClient(NewMemory(Synchronous())).Enqueue(context.Background(), task, resize.Options(asynq.MaxRetry(1))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Retention != time.Hour || info.MaxRetry != 1 {
+		t.Errorf("got retention %s and %d retries, want the default retention and the given retries", info.Retention, info.MaxRetry)
+	}
+}
+
+func enqueueTo[P any](task *Task[P], payload P) func(ctx context.Context, e Enqueuer) (*TaskInfo, error) {
+	return func(ctx context.Context, e Enqueuer) (*TaskInfo, error) {
+		return task.EnqueueTo(ctx, e, payload)
+	}
+}
+
+func decode[P any](task *Task[P]) func(payload []byte) error {
+	return func(payload []byte) error {
+		_, err := task.Decode(asynq.NewTask(task.Name(), payload))
+
+		return err
+	}
+}
+
+func TestDecode(t *testing.T) {
+	orders := decode(NewTask[order]("test:order"))
+	refunds := decode(NewTask[refund]("test:refund"))
+
+	tests := []struct {
+		name    string
+		decode  func(payload []byte) error
+		payload string
+		invalid bool
+	}{
+		{"valid", orders, `{"id":"1"}`, false},
+		{"malformed", orders, `{`, true},
+		{"invalid value receiver", orders, `{}`, true},
+		{"invalid pointer receiver", refunds, `{"amount":0}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode([]byte(tt.payload))
+
+			if !tt.invalid {
+				if err != nil {
+					t.Errorf("got %v, want the payload", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, asynq.SkipRetry) {
+				t.Errorf("got %v, want an error wrapping asynq.SkipRetry", err)
+			}
+		})
+	}
+}
+
+func TestHandleSkipsRetryOfInvalidPayloads(t *testing.T) {
+	called := false
+
+	h := NewTask[order]("test:order").Handle(func(ctx context.Context, payload order) error {
+		called = true
+
+		return nil
+	})
+
+	err := h.FN.ProcessTask(context.Background(), asynq.NewTask("test:order", []byte(`{}`)))
+
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("got %v, want an error wrapping asynq.SkipRetry", err)
+	}
+
+	if called {
+		t.Error("the handler was called with an invalid payload")
+	}
+
+	if err := h.FN.ProcessTask(context.Background(), asynq.NewTask("test:order", []byte(`{"id":"1"}`))); err != nil || !called {
+		t.Errorf("got %v, want the handler called with a valid payload", err)
+	}
+}
@@ -0,0 +1,109 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx buffers tasks while a database transaction is open, Commit enqueues them after the transaction committed.
+// Buffered tasks are lost when the process stops between the two commits, use the outbox package when that matters.
+type Tx struct {
+	client *Client
+	mu     sync.Mutex
+	tasks  []pending
+}
+
+type pending struct {
+	task *asynq.Task
+	opts []asynq.Option
+}
+
+// Begin starts buffering tasks
+func (c *Client) Begin() *Tx {
+	return &Tx{
+		client: c,
+	}
+}
+
+// WithTx runs fn in a database transaction and enqueues the tasks fn buffered in tx once it committed.
+// Nothing is enqueued when fn fails or the transaction cannot commit.
+func (c *Client) WithTx(ctx context.Context, db *sqlx.DB, fn func(dbtx *sqlx.Tx, tx *Tx) error) error {
+	dbtx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	tx := c.Begin()
+
+	if err := fn(dbtx, tx); err != nil {
+		tx.Rollback()
+
+		if rbErr := dbtx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w, rolling back: %v", err, rbErr)
+		}
+
+		return err
+	}
+
+	if err := dbtx.Commit(); err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	if _, err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("transaction committed, but tasks were not enqueued: %w", err)
+	}
+
+	return nil
+}
+
+// Enqueue buffers a task
+func (tx *Tx) Enqueue(task *asynq.Task, opts ...asynq.Option) *Tx {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.tasks = append(tx.tasks, pending{task: task, opts: opts})
+
+	return tx
+}
+
+// Commit enqueues the buffered tasks, see Client.EnqueueBatch for the results
+func (tx *Tx) Commit(ctx context.Context) ([]*TaskInfo, error) {
+	tx.mu.Lock()
+	tasks := tx.tasks
+	tx.tasks = nil
+	tx.mu.Unlock()
+
+	infos := make([]*TaskInfo, len(tasks))
+	errs := map[int]error{}
+
+	for i, p := range tasks {
+		info, err := tx.client.Enqueue(ctx, p.task, p.opts...)
+		if err != nil {
+			errs[i] = err
+
+			continue
+		}
+
+		infos[i] = info
+	}
+
+	if len(errs) > 0 {
+		return infos, &BatchError{Total: len(tasks), Errors: errs}
+	}
+
+	return infos, nil
+}
+
+// Rollback drops the buffered tasks
+func (tx *Tx) Rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.tasks = nil
+}
@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// uniqueKey identifies a task for asynq.Unique by queue, type and payload, leaving out the envelope
+// so the same payload enqueued under different traces or requests is still a duplicate
+func uniqueKey(queue, typename string, payload []byte) string {
+	sum := md5.Sum(stripEnvelope(payload))
+
+	return "gwf:unique:" + queue + ":" + typename + ":" + hex.EncodeToString(sum[:])
+}
+
+// uniqueOptions returns the queue and the asynq.Unique TTL of opts, later options win
+func uniqueOptions(opts []asynq.Option) (queue string, ttl time.Duration) {
+	queue = "default"
+
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.QueueOpt:
+			queue = opt.Value().(string)
+		case asynq.UniqueOpt:
+			ttl = opt.Value().(time.Duration)
+		}
+	}
+
+	return queue, ttl
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/khvh/gwf/pkg/queue"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// Async creates a POST route enqueueing the request body as task, it responds 202 Accepted with the status of the task
+// and a Location to the status route at path/:id, which is registered along with it, see TaskStatus.
+// Enqueue the task with asynq.Retention to keep its status and result once it completed.
+func Async[Req, Res interface{}](path string, task *queue.Task[Req], handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	var req Req
+
+	status := statusRoute[Res](strings.TrimSuffix(path, "/")+"/:id", getPackage(pc), task.Name(), handlers)
+	status.suffix = "Status"
+
+	return &Route{
+		path: path,
+		spec: spec.Of(path, getPackage(pc)).
+			Post(queue.TaskStatus[Res]{}, req, http.StatusAccepted).
+			AddResponse(queue.TaskStatus[Res]{}, http.StatusAccepted, spec.ResHeader("Location", "URL of the task status")).
+			DropResponses(http.StatusNotFound),
+		methods: []string{http.MethodPost},
+		handler: enqueue[Req, Res](task),
+		mw:      handlers,
+		related: []*Route{status},
+	}
+}
+
+func enqueue[Req, Res interface{}](task *queue.Task[Req]) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var payload Req
+
+		if err := bind(c, &payload); err != nil {
+			return c.JSON(http.StatusBadRequest, spec.Err("invalid_body").Message(err.Error()))
+		}
+
+		if err := task.Validate(payload); err != nil {
+			return c.JSON(http.StatusBadRequest, spec.Err("invalid_payload").Message(err.Error()))
+		}
+
+		info, err := task.Enqueue(c.Request().Context(), payload)
+		if err != nil {
+			return err
+		}
+
+		status, err := queue.Status[Res](info)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set(echo.HeaderLocation, strings.TrimSuffix(c.Request().URL.Path, "/")+"/"+info.ID)
+
+		return c.JSON(http.StatusAccepted, status)
+	}
+}
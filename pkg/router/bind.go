@@ -0,0 +1,101 @@
+package router
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	fileHeaderType  = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// bind decodes the request body into target. Raw bodies are read into []byte and string targets,
+// multipart file fields are bound to *multipart.FileHeader and []*multipart.FileHeader fields by their form tag.
+func bind(c echo.Context, target interface{}) error {
+	switch t := target.(type) {
+	case *[]byte:
+		body, err := io.ReadAll(c.Request().Body)
+		*t = body
+
+		return err
+	case *string:
+		body, err := io.ReadAll(c.Request().Body)
+		*t = string(body)
+
+		return err
+	}
+
+	if err := c.Bind(target); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return nil
+	}
+
+	return bindFiles(c, target)
+}
+
+func bindFiles(c echo.Context, target interface{}) error {
+	v := reflect.ValueOf(target)
+
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		files := form.File[name]
+
+		if name == "" || len(files) == 0 || !v.Field(i).CanSet() {
+			continue
+		}
+
+		switch field.Type {
+		case fileHeaderType:
+			v.Field(i).Set(reflect.ValueOf(files[0]))
+		case fileHeadersType:
+			v.Field(i).Set(reflect.ValueOf(files))
+		}
+	}
+
+	return nil
+}
+
+// consumes rejects request bodies that are not one of the given content types
+func consumes(types []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			if req.ContentLength == 0 || req.Method == http.MethodGet || req.Method == http.MethodHead {
+				return next(c)
+			}
+
+			contentType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+
+			for _, t := range types {
+				if t == contentType {
+					return next(c)
+				}
+			}
+
+			return echo.ErrUnsupportedMediaType
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var deprecatedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "deprecated_route_calls_total",
+		Help: "The total number of calls to deprecated routes",
+	},
+	[]string{"method", "path", "client"},
+)
+
+// ClientID identifies the caller of a deprecated route, it is reported by the deprecated_route_calls_total metric
+// when it is one of the clients given to TrackClients. Defaults to the X-Client-ID header,
+// falling back to the product token of the User-Agent.
+var ClientID = func(c echo.Context) string {
+	if id := c.Request().Header.Get("X-Client-ID"); id != "" {
+		return id
+	}
+
+	return strings.Split(c.Request().UserAgent(), "/")[0]
+}
+
+var (
+	clientsMu sync.RWMutex
+	clients   = map[string]bool{}
+)
+
+// TrackClients sets the clients reported by the deprecated_route_calls_total metric, others are reported as "other".
+// Client IDs come from request headers, the list keeps the number of label values bounded.
+func TrackClients(ids ...string) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	clients = map[string]bool{}
+
+	for _, id := range ids {
+		clients[id] = true
+	}
+}
+
+// clientLabel returns the client ID when it is tracked, "other" otherwise
+func clientLabel(c echo.Context) string {
+	id := ClientID(c)
+
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	if clients[id] {
+		return id
+	}
+
+	return "other"
+}
+
+// deprecated holds what a deprecated route or version advertises
+type deprecated struct {
+	since  time.Time
+	sunset time.Time
+	link   string
+}
+
+// deprecation advertises the deprecation with the headers of RFC 9745 and RFC 8594. The Deprecation date is
+// fixed by the caller so that it stays the same across restarts and replicas, without one it is the Unix epoch,
+// the route is deprecated since an unknown date.
+func deprecation(d *deprecated) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+
+			// RFC 9745 dates are @ followed by Unix seconds
+			since := int64(0)
+			if !d.since.IsZero() {
+				since = d.since.Unix()
+			}
+
+			header.Set("Deprecation", fmt.Sprintf("@%d", since))
+
+			if !d.sunset.IsZero() {
+				header.Set("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+			}
+
+			if d.link != "" {
+				header.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.link))
+			}
+
+			deprecatedCounter.WithLabelValues(c.Request().Method, c.Path(), clientLabel(c)).Inc()
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDeprecationClientLabel(t *testing.T) {
+	TrackClients("web", "ios")
+	defer TrackClients()
+
+	tests := []struct {
+		name      string
+		header    string
+		userAgent string
+		label     string
+	}{
+		{"tracked client id", "web", "", "web"},
+		{"tracked user agent", "", "ios/1.2.3", "ios"},
+		{"untracked client id", "attacker-123", "", "other"},
+		{"untracked user agent", "", "curl/8.0", "other"},
+		{"anonymous", "", "", "other"},
+	}
+
+	e := echo.New()
+	handler := deprecation(&deprecated{since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/old", nil)
+			req.Header.Set("X-Client-ID", tt.header)
+			req.Header.Set("User-Agent", tt.userAgent)
+
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetPath("/old")
+
+			counter := deprecatedCounter.WithLabelValues(http.MethodGet, "/old", tt.label)
+			before := testutil.ToFloat64(counter)
+
+			if err := handler(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("%s calls counted %v times", tt.label, got)
+			}
+
+			// RFC 9745 dates are @ followed by Unix seconds
+			if got := c.Response().Header().Get("Deprecation"); got != "@1767225600" {
+				t.Errorf("got Deprecation %q, want @1767225600", got)
+			}
+		})
+	}
+}
+
+func TestDeprecatedHeaders(t *testing.T) {
+	sunset := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		route       *Route
+		deprecation string
+	}{
+		{"without a date", Get[[]file]("/old", list).Deprecated(sunset, "/new"), "@0"},
+		{"since a date", Get[[]file]("/old", list).Deprecated(sunset, "/new").DeprecatedSince(since), "@1767225600"},
+		{"date set first", Get[[]file]("/old", list).DeprecatedSince(since).Deprecated(sunset, "/new"), "@1767225600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+
+			if err := Instance().Register(tt.route).Build(InitReflector(), e); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old", nil))
+
+			want := map[string]string{
+				"Deprecation": tt.deprecation,
+				"Sunset":      "Wed, 02 Jan 2030 03:04:05 GMT",
+				"Link":        `</new>; rel="successor-version"`,
+			}
+
+			for name, value := range want {
+				if got := rec.Header().Get(name); got != value {
+					t.Errorf("got %s %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
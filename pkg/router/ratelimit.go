@@ -0,0 +1,20 @@
+package router
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter limits requests per client IP, rejected requests get 429
+func rateLimiter(rps float64, burst int) echo.MiddlewareFunc {
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(rps),
+			Burst:     burst,
+			ExpiresIn: 3 * time.Minute,
+		}),
+	})
+}
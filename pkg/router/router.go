@@ -5,8 +5,10 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/khvh/gwf/pkg/config"
 	"github.com/khvh/gwf/pkg/spec"
@@ -24,11 +26,18 @@ type Ctx[B interface{}, P interface{}] struct {
 // Route is a structure for holding data for building OpenAPI spec
 // and handling requests with Fiber
 type Route struct {
-	path    string
-	method  string
-	spec    *spec.OAS
-	handler echo.HandlerFunc
-	mw      []echo.MiddlewareFunc
+	path        string
+	methods     []string
+	operationID string
+	suggestedID string
+	consumes    []string
+	spec        *spec.OAS
+	built       []*spec.OAS
+	handler     echo.HandlerFunc
+	mw          []echo.MiddlewareFunc
+	deprecated  *deprecated
+	related     []*Route
+	suffix      string
 }
 
 // Summary adds a summary to the route
@@ -38,6 +47,18 @@ func (r *Route) Summary(summary string) *Route {
 	return r
 }
 
+// OperationID overrides the operationId derived from the handler name, it must be unique.
+// Routes registered along with it get it followed by their suffix, e.g. Status for the status route of Async.
+func (r *Route) OperationID(id string) *Route {
+	r.operationID = id
+
+	for _, related := range r.related {
+		related.operationID = id + related.suffix
+	}
+
+	return r
+}
+
 // Tags add tags for the route
 func (r *Route) Tags(tags ...string) *Route {
 	r.spec.AddTags(tags...)
@@ -53,31 +74,147 @@ func (r *Route) Description(description string) *Route {
 }
 
 // Query sets a query param
-func (r *Route) Query(name string) *Route {
-	r.spec.AddQueryParam(name)
+func (r *Route) Query(name string, opts ...spec.ParamOption) *Route {
+	r.spec.AddQueryParam(name, opts...)
+
+	return r
+}
+
+// Header sets a header param
+func (r *Route) Header(name string, opts ...spec.ParamOption) *Route {
+	r.spec.AddHeaderParam(name, opts...)
+
+	return r
+}
+
+// Cookie sets a cookie param
+func (r *Route) Cookie(name string, opts ...spec.ParamOption) *Route {
+	r.spec.AddCookieParam(name, opts...)
+
+	return r
+}
+
+// Res adds a response to spec, options add a description, headers, examples or drop the body
+func (r *Route) Res(body interface{}, code int, opts ...spec.ResponseOption) *Route {
+	r.spec.AddResponse(body, code, opts...)
+
+	return r
+}
+
+// Example adds a named example of the request body
+func (r *Route) Example(name string, value interface{}) *Route {
+	r.spec.AddRequestExample(name, value)
+
+	return r
+}
+
+// Consumes sets the content types accepted for the request body, other content types are rejected with 415
+func (r *Route) Consumes(types ...string) *Route {
+	r.spec.AddConsumes(types...)
+	r.consumes = append(r.consumes, types...)
+
+	return r
+}
+
+// Produces sets the content types of successful responses
+func (r *Route) Produces(types ...string) *Route {
+	r.spec.AddProduces(types...)
+
+	return r
+}
+
+// Errors replaces the default error responses documented for the route
+func (r *Route) Errors(codes ...int) *Route {
+	r.spec.SetErrors(codes...)
+
+	return r
+}
+
+// ErrorBody sets the body documented for the error responses of the route
+func (r *Route) ErrorBody(body interface{}) *Route {
+	r.spec.SetErrorBody(body)
+
+	return r
+}
+
+// WithoutRes drops default error responses from the spec, e.g. 404 for routes that cannot miss
+func (r *Route) WithoutRes(codes ...int) *Route {
+	r.spec.DropResponses(codes...)
 
 	return r
 }
 
-// Header sets a query param
-func (r *Route) Header(name string) *Route {
-	r.spec.AddHeaderParam(name)
+// RateLimit limits requests to the route per client IP and documents the 429 response
+func (r *Route) RateLimit(rps float64, burst int) *Route {
+	r.spec.RateLimited()
+	r.mw = append([]echo.MiddlewareFunc{rateLimiter(rps, burst)}, r.mw...)
 
 	return r
 }
 
-// Res adds a response to spec
-func (r *Route) Res(body interface{}, code int) *Route {
-	r.spec.AddResponse(body, code)
+// Security adds a security requirement for the route, overriding the one inherited from routers
+func (r *Route) Security(name string, scopes ...string) *Route {
+	r.spec.AddSecurity(name, scopes...)
 
 	return r
 }
 
+// Deprecated marks the route as deprecated, responses advertise the deprecation, the sunset date and the replacement link.
+// Calls are counted by the deprecated_route_calls_total metric, per client only for the clients given to TrackClients.
+func (r *Route) Deprecated(sunset time.Time, link string) *Route {
+	d := r.deprecation()
+	d.sunset = sunset
+	d.link = link
+
+	r.spec.Deprecate(sunset)
+
+	return r
+}
+
+// DeprecatedSince marks the route as deprecated since the given date, advertised by the Deprecation header
+func (r *Route) DeprecatedSince(since time.Time) *Route {
+	r.deprecation().since = since
+	r.spec.Deprecate(r.deprecated.sunset)
+
+	return r
+}
+
+func (r *Route) deprecation() *deprecated {
+	if r.deprecated == nil {
+		r.deprecated = &deprecated{}
+		r.mw = append([]echo.MiddlewareFunc{deprecation(r.deprecated)}, r.mw...)
+	}
+
+	return r.deprecated
+}
+
 // Router holds the reference for openapi3.Reflector and routes
 type Router struct {
-	prefix string
-	group  string
-	routes []*Route
+	prefix   string
+	group    string
+	tags     []string
+	security []map[string][]string
+	mw       []echo.MiddlewareFunc
+	version  *Version
+	errors   []int
+	errBody  interface{}
+	limited  bool
+	routes   []*Route
+	children []*Router
+}
+
+// scope holds the settings a router passes down to its mounted children
+type scope struct {
+	prefix   string
+	group    string
+	tags     []string
+	security []map[string][]string
+	mw       []echo.MiddlewareFunc
+	version  *Version
+	errors   []int
+	errBody  interface{}
+	limited  bool
+	reserved map[string]bool
 }
 
 //var (
@@ -97,6 +234,8 @@ func InitReflector() *openapi3.Reflector {
 	conf := config.Get()
 	ref := &openapi3.Reflector{}
 
+	ref.InterceptDefName(genericDefName)
+
 	ref.Spec = &openapi3.Spec{Openapi: "3.0.3"}
 
 	servers := []openapi3.Server{
@@ -113,32 +252,54 @@ func InitReflector() *openapi3.Reflector {
 
 	ref.Spec.Info.
 		WithTitle(conf.OAS.Title).
-		WithVersion(conf.OAS.Description).
+		WithVersion(conf.OAS.Version).
 		WithDescription(conf.OAS.Description)
 
+	bearer := &openapi3.SecurityScheme{
+		OAuth2SecurityScheme: (&openapi3.OAuth2SecurityScheme{}).
+			WithFlows(openapi3.OAuthFlows{
+				Implicit: &openapi3.ImplicitOAuthFlow{
+					AuthorizationURL: conf.OAuth.IssuerURL,
+					Scopes:           map[string]string{"openid": "OpenID Connect"},
+				},
+			}),
+	}
+
+	// Without an issuer, e.g. when exporting the spec without config, tokens are documented as plain bearer tokens
+	if conf.OAuth.IssuerURL == "" {
+		bearer = &openapi3.SecurityScheme{
+			HTTPSecurityScheme: (&openapi3.HTTPSecurityScheme{}).WithScheme("bearer"),
+		}
+	}
+
 	ref.SpecEns().ComponentsEns().SecuritySchemesEns().WithMapOfSecuritySchemeOrRefValuesItem(
 		"bearer",
 		openapi3.SecuritySchemeOrRef{
-			SecurityScheme: &openapi3.SecurityScheme{
-				OAuth2SecurityScheme: (&openapi3.OAuth2SecurityScheme{}).
-					WithFlows(openapi3.OAuthFlows{
-						Implicit: &openapi3.ImplicitOAuthFlow{
-							AuthorizationURL: conf.OAuth.IssuerURL,
-							Scopes:           map[string]string{},
-						},
-					}),
-			},
+			SecurityScheme: bearer,
 		},
 	)
 
 	return ref
 }
 
-// Register registers one or more routes
+// supportedMethods holds the methods that can be both routed and described in OpenAPI
+var supportedMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Register registers one or more routes, with the routes they bring along, e.g. the status route of Async
 func (r *Router) Register(routes ...*Route) *Router {
 
 	for _, route := range routes {
 		r.routes = append(r.routes, route)
+		r.routes = append(r.routes, route.related...)
 	}
 
 	return r
@@ -158,53 +319,280 @@ func (r *Router) Group(name string) *Router {
 	return r
 }
 
+// Tags adds tags to every route of the router and its children
+func (r *Router) Tags(tags ...string) *Router {
+	r.tags = append(r.tags, tags...)
+
+	return r
+}
+
+// Security adds a security requirement to every route of the router and its children
+func (r *Router) Security(name string, scopes ...string) *Router {
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	r.security = append(r.security, map[string][]string{name: scopes})
+
+	return r
+}
+
+// Use adds middleware to every route of the router and its children
+func (r *Router) Use(mw ...echo.MiddlewareFunc) *Router {
+	r.mw = append(r.mw, mw...)
+
+	return r
+}
+
+// Errors replaces the default error responses documented for every route of the router and its children
+func (r *Router) Errors(codes ...int) *Router {
+	r.errors = append([]int{}, codes...)
+
+	return r
+}
+
+// ErrorBody sets the body documented for error responses of the router and its children
+func (r *Router) ErrorBody(body interface{}) *Router {
+	r.errBody = body
+
+	return r
+}
+
+// RateLimit limits requests per client IP across all routes of the router and its children
+func (r *Router) RateLimit(rps float64, burst int) *Router {
+	r.limited = true
+	r.mw = append(r.mw, rateLimiter(rps, burst))
+
+	return r
+}
+
+// Version assigns the router and its children to an API version
+func (r *Router) Version(version *Version) *Router {
+	r.version = version
+
+	return r
+}
+
+// APIVersion returns the API version assigned to the router
+func (r *Router) APIVersion() *Version {
+	return r.version
+}
+
+// Mount nests routers under this one, they inherit prefix, tags, security and middleware
+func (r *Router) Mount(routers ...*Router) *Router {
+	r.children = append(r.children, routers...)
+
+	return r
+}
+
 // Build builds the OpenAPI spec and registers handlers with Echo
-func (r *Router) Build(ref *openapi3.Reflector, app *echo.Echo) {
+func (r *Router) Build(ref *openapi3.Reflector, app *echo.Echo) error {
+	return Build(ref, app, r)
+}
+
+// Build builds the routers, operationIds set with Route.OperationID take precedence over the derived ones.
+// A derived operationId that is already used falls back to the one derived from the method and path,
+// also when it was documented by an earlier Build on the same reflector and is set explicitly later.
+func Build(ref *openapi3.Reflector, app *echo.Echo, routers ...*Router) error {
+	reserved := map[string]bool{}
+
+	for _, r := range routers {
+		r.reserve(reserved)
+	}
+
+	for _, r := range routers {
+		if err := r.build(ref, app.Group(""), &scope{reserved: reserved}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reserve collects the operationIds set explicitly on the routes of the router and its children
+func (r *Router) reserve(ids map[string]bool) {
 	for _, route := range r.routes {
-		if r.prefix != "" {
-			route.spec.AddPrefix(r.prefix)
+		if route.operationID != "" {
+			ids[route.operationID] = true
 		}
+	}
+
+	for _, child := range r.children {
+		child.reserve(ids)
+	}
+}
 
-		if r.group != "" {
-			route.spec.ReplaceTags(r.group)
+// Operations returns the operations of the router and its children, paths include prefixes once the router is built.
+// A router mounted in several places has the operations of every place.
+func (r *Router) Operations() []spec.Operation {
+	var ops []spec.Operation
+
+	for _, route := range r.routes {
+		if len(route.built) == 0 {
+			ops = append(ops, route.spec.Operations()...)
 		}
 
-		route.spec.Build(ref)
+		for _, built := range route.built {
+			ops = append(ops, built.Operations()...)
+		}
+	}
 
-		r.useRoute(route, app)
+	for _, child := range r.children {
+		ops = append(ops, child.Operations()...)
 	}
+
+	return ops
 }
 
-func (r *Router) useRoute(route *Route, app *echo.Echo) {
-	if r.prefix == "" {
-		switch route.method {
-		case http.MethodGet:
-			app.GET(route.path, route.handler, route.mw...)
-		case http.MethodDelete:
-			app.DELETE(route.path, route.handler, route.mw...)
-		case http.MethodPost:
-			app.POST(route.path, route.handler, route.mw...)
-		case http.MethodPut:
-			app.PUT(route.path, route.handler, route.mw...)
-		case http.MethodPatch:
-			app.PATCH(route.path, route.handler, route.mw...)
+func (r *Router) build(ref *openapi3.Reflector, parent *echo.Group, inherited *scope) error {
+	s := r.inherit(inherited)
+	group := parent.Group(r.localPrefix())
+
+	if s.version != nil {
+		ref = s.version.Reflector()
+	}
+
+	for _, route := range r.routes {
+		for _, method := range route.methods {
+			if !supportedMethods[method] {
+				return fmt.Errorf("unsupported method %s for route %s", method, route.path)
+			}
+		}
+
+		// The route may be mounted in several places, each one documents a copy with its own prefix and tags
+		oas := route.spec.Clone()
+
+		if s.prefix != "" {
+			oas.AddPrefix(s.prefix)
+		}
+
+		if s.group != "" {
+			oas.ReplaceTags(s.group)
+		}
+
+		if s.version != nil && s.version.deprecated {
+			oas.Deprecate(s.version.sunset)
 		}
-	} else {
-		app := app.Group(r.prefix)
-
-		switch route.method {
-		case http.MethodGet:
-			app.GET(route.path, route.handler, route.mw...)
-		case http.MethodDelete:
-			app.DELETE(route.path, route.handler, route.mw...)
-		case http.MethodPost:
-			app.POST(route.path, route.handler, route.mw...)
-		case http.MethodPut:
-			app.PUT(route.path, route.handler, route.mw...)
-		case http.MethodPatch:
-			app.PATCH(route.path, route.handler, route.mw...)
+
+		if s.version != nil && s.version.versioning.strategy == HeaderStrategy {
+			oas.AddHeaderParam(s.version.versioning.header)
+		}
+
+		oas.
+			AddTags(s.tags...).
+			InheritSecurity(s.security).
+			InheritErrors(s.errors).
+			InheritErrorBody(s.errBody)
+
+		if s.limited {
+			oas.RateLimited()
+		}
+
+		if route.operationID != "" {
+			oas.SetOperationID(route.operationID)
+		} else if id := route.derivedID(); id != "" && !s.reserved[id] {
+			oas.SuggestOperationID(id)
+		}
+
+		if err := oas.Build(ref); err != nil {
+			return err
+		}
+
+		route.built = append(route.built, oas)
+
+		mw := append([]echo.MiddlewareFunc{}, s.mw...)
+
+		if len(route.consumes) > 0 {
+			mw = append(mw, consumes(route.consumes))
+		}
+
+		mw = append(mw, route.mw...)
+
+		for _, method := range route.methods {
+			if s.version == nil || s.version.versioning.strategy == PathStrategy {
+				group.Add(method, route.path, route.handler, mw...)
+
+				continue
+			}
+
+			s.version.versioning.register(
+				group,
+				method,
+				route.path,
+				method+" "+joinPrefix(s.prefix, route.path),
+				s.version,
+				applyMiddleware(route.handler, mw...),
+			)
 		}
 	}
+
+	for _, child := range r.children {
+		if err := child.build(ref, group, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) inherit(parent *scope) *scope {
+	s := &scope{
+		prefix:   joinPrefix(parent.prefix, r.localPrefix()),
+		group:    parent.group,
+		tags:     append(append([]string{}, parent.tags...), r.tags...),
+		security: parent.security,
+		mw:       append([]echo.MiddlewareFunc{}, parent.mw...),
+		version:  parent.version,
+		errors:   parent.errors,
+		errBody:  parent.errBody,
+		limited:  parent.limited || r.limited,
+		reserved: parent.reserved,
+	}
+
+	if r.errors != nil {
+		s.errors = r.errors
+	}
+
+	if r.errBody != nil {
+		s.errBody = r.errBody
+	}
+
+	if r.group != "" {
+		s.group = r.group
+	}
+
+	if len(r.security) > 0 {
+		s.security = r.security
+	}
+
+	if r.version != nil {
+		s.version = r.version
+		s.mw = append(s.mw, r.version.middleware()...)
+	}
+
+	s.mw = append(s.mw, r.mw...)
+
+	return s
+}
+
+func (r *Router) localPrefix() string {
+	if r.version == nil {
+		return r.prefix
+	}
+
+	return r.version.prefix(r.prefix)
+}
+
+func joinPrefix(parent, prefix string) string {
+	if parent == "" {
+		return prefix
+	}
+
+	if prefix == "" {
+		return parent
+	}
+
+	return strings.TrimSuffix(parent, "/") + "/" + strings.TrimPrefix(prefix, "/")
 }
 
 // Get creates a GET route
@@ -216,7 +604,37 @@ func Get[T interface{}](path string, handlerFunc echo.HandlerFunc, handlers ...e
 	return &Route{
 		path:    path,
 		spec:    spec.Of(path, getPackage(pc)).Get(t),
-		method:  http.MethodGet,
+		methods: []string{http.MethodGet},
+		handler: handlerFunc,
+		mw:      handlers,
+	}
+}
+
+// Head creates a HEAD route
+func Head[T interface{}](path string, handlerFunc echo.HandlerFunc, handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	var t T
+
+	return &Route{
+		path:    path,
+		spec:    spec.Of(path, getPackage(pc)).Head(t),
+		methods: []string{http.MethodHead},
+		handler: handlerFunc,
+		mw:      handlers,
+	}
+}
+
+// Options creates an OPTIONS route
+func Options[T interface{}](path string, handlerFunc echo.HandlerFunc, handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	var t T
+
+	return &Route{
+		path:    path,
+		spec:    spec.Of(path, getPackage(pc)).Options(t),
+		methods: []string{http.MethodOptions},
 		handler: handlerFunc,
 		mw:      handlers,
 	}
@@ -231,7 +649,7 @@ func Delete[T interface{}](path string, handlerFunc echo.HandlerFunc, handlers .
 	return &Route{
 		path:    path,
 		spec:    spec.Of(path, getPackage(pc)).Delete(t),
-		method:  http.MethodDelete,
+		methods: []string{http.MethodDelete},
 		handler: handlerFunc,
 		mw:      handlers,
 	}
@@ -249,7 +667,7 @@ func Post[T interface{}, D interface{}](path string, handlerFunc echo.HandlerFun
 	return &Route{
 		path:    path,
 		spec:    spec.Of(path, getPackage(pc)).Post(t, d),
-		method:  http.MethodPost,
+		methods: []string{http.MethodPost},
 		handler: handlerFunc,
 		mw:      handlers,
 	}
@@ -267,7 +685,7 @@ func Put[T interface{}, D interface{}](path string, handlerFunc echo.HandlerFunc
 	return &Route{
 		path:    path,
 		spec:    spec.Of(path, getPackage(pc)).Put(t, d),
-		method:  http.MethodPut,
+		methods: []string{http.MethodPut},
 		handler: handlerFunc,
 		mw:      handlers,
 	}
@@ -285,7 +703,60 @@ func Patch[T interface{}, D interface{}](path string, handlerFunc echo.HandlerFu
 	return &Route{
 		path:    path,
 		spec:    spec.Of(path, getPackage(pc)).Patch(t, d),
-		method:  http.MethodPatch,
+		methods: []string{http.MethodPatch},
+		handler: handlerFunc,
+		mw:      handlers,
+	}
+}
+
+// Any creates a route matching every method that can be described in OpenAPI
+func Any[T interface{}, D interface{}](path string, handlerFunc echo.HandlerFunc, handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	methods := []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+		http.MethodTrace,
+	}
+
+	var (
+		t T
+		d D
+	)
+
+	return &Route{
+		path:    path,
+		spec:    spec.Of(path, getPackage(pc)).Match(methods, t, d),
+		methods: methods,
+		handler: handlerFunc,
+		mw:      handlers,
+	}
+}
+
+// Match creates a route for the given methods
+func Match[T interface{}, D interface{}](methods []string, path string, handlerFunc echo.HandlerFunc, handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	var (
+		t T
+		d D
+	)
+
+	upper := make([]string, len(methods))
+
+	for i, method := range methods {
+		upper[i] = strings.ToUpper(method)
+	}
+
+	return &Route{
+		path:    path,
+		spec:    spec.Of(path, getPackage(pc)).Match(upper, t, d),
+		methods: upper,
 		handler: handlerFunc,
 		mw:      handlers,
 	}
@@ -298,7 +769,7 @@ func GetCtx[B interface{}, P interface{}](c echo.Context) *Ctx[B, P] {
 		p P
 	)
 
-	if err := c.Bind(&b); err != nil {
+	if err := bind(c, &b); err != nil {
 		log.Trace().Err(err).Send()
 	}
 
@@ -325,4 +796,71 @@ func getPackage(pc uintptr) string {
 	caser := cases.Title(language.English)
 
 	return caser.String(strings.ToLower(funcName[:lastDot]))
-}
\ No newline at end of file
+}
+
+// derivedID returns the operationId used when none is set, derived from the handler name unless the route suggests one
+func (r *Route) derivedID() string {
+	if r.suggestedID != "" {
+		return r.suggestedID
+	}
+
+	return operationID(r.handler)
+}
+
+// operationID derives an operationId from the handler name, e.g. (*UserController).List becomes userControllerList.
+// Type parameters are left out, (*Store[...]).List becomes storeList. Anonymous functions have no usable name and
+// return an empty string.
+func operationID(handler echo.HandlerFunc) string {
+	funcName := strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(), "-fm")
+	funcName = strings.ReplaceAll(funcName, "[...]", "")
+	funcName = funcName[strings.LastIndexByte(funcName, '/')+1:]
+
+	parts := strings.Split(funcName, ".")[1:]
+
+	for i, part := range parts {
+		if strings.HasPrefix(part, "func") && strings.Trim(part[4:], "0123456789") == "" {
+			return ""
+		}
+
+		parts[i] = strings.Trim(part, "(*)")
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	id := strings.Join(parts, "")
+
+	return strings.ToLower(id[:1]) + id[1:]
+}
+
+// genericDefName shortens schema names of generic types, e.g. QueueTaskStatus[github.com/khvh/gwf/pkg.Export]
+// becomes QueueTaskStatusExport, brackets and slashes are not valid in component names
+func genericDefName(_ reflect.Type, name string) string {
+	i := strings.IndexByte(name, '[')
+	if i < 0 || !strings.HasSuffix(name, "]") {
+		return name
+	}
+
+	var b strings.Builder
+
+	b.WriteString(name[:i])
+
+	for _, arg := range strings.Split(name[i+1:len(name)-1], ",") {
+		arg = strings.TrimPrefix(strings.TrimSpace(arg), "*")
+
+		for strings.HasPrefix(arg, "[]") {
+			b.WriteString("List")
+
+			arg = strings.TrimPrefix(arg[2:], "*")
+		}
+
+		arg = arg[strings.LastIndexAny(arg, "./")+1:]
+
+		if arg != "" {
+			b.WriteString(strings.ToUpper(arg[:1]) + arg[1:])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,656 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swaggest/openapi-go/openapi3"
+
+	"github.com/khvh/gwf/pkg/queue"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+type file struct {
+	Name string `json:"name"`
+}
+
+func list(c echo.Context) error {
+	return c.JSON(http.StatusOK, []file{})
+}
+
+func download(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func operationIDs(s *openapi3.Spec) map[string]string {
+	ids := map[string]string{}
+
+	for path, item := range s.Paths.MapOfPathItemValues {
+		for method, op := range item.MapOfOperationValues {
+			ids[strings.ToUpper(method)+" "+path] = *op.ID
+		}
+	}
+
+	return ids
+}
+
+func TestOperationIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []*Route
+		want   map[string]string
+	}{
+		{
+			name:   "derived from the handler",
+			routes: []*Route{Get[[]file]("/files", list)},
+			want:   map[string]string{"GET /files": "list"},
+		},
+		{
+			name:   "handler shared by several routes",
+			routes: []*Route{Get[[]file]("/files", list), Get[[]file]("/folders", list)},
+			want:   map[string]string{"GET /files": "list", "GET /folders": "getFolders"},
+		},
+		{
+			name:   "GET and HEAD sharing a handler",
+			routes: []*Route{Get[file]("/files/:id", download), Head[file]("/files/:id", download)},
+			want:   map[string]string{"GET /files/{id}": "download", "HEAD /files/{id}": "headFilesById"},
+		},
+		{
+			name:   "explicit operationId wins over a derived one",
+			routes: []*Route{Get[[]file]("/files", list), Get[[]file]("/all", download).OperationID("list")},
+			want:   map[string]string{"GET /files": "getFiles", "GET /all": "list"},
+		},
+		{
+			name:   "status route of an async route",
+			routes: []*Route{Async[file, file]("/exports", queue.NewTask[file]("test:export")).OperationID("export")},
+			want:   map[string]string{"POST /exports": "export", "GET /exports/{id}": "exportStatus"},
+		},
+		{
+			name:   "closure",
+			routes: []*Route{Get[[]file]("/files", func(c echo.Context) error { return nil })},
+			want:   map[string]string{"GET /files": "getFiles"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := InitReflector()
+
+			if err := Instance().Register(tt.routes...).Build(ref, echo.New()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := operationIDs(ref.Spec)
+
+			for route, id := range tt.want {
+				if got[route] != id {
+					t.Errorf("%s: operationId %q, want %q", route, got[route], id)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateExplicitOperationID(t *testing.T) {
+	err := Instance().
+		Register(
+			Get[[]file]("/files", list).OperationID("listFiles"),
+			Get[[]file]("/folders", list).OperationID("listFiles"),
+		).
+		Build(InitReflector(), echo.New())
+
+	if err == nil || !strings.Contains(err.Error(), "duplicate operationId listFiles") {
+		t.Fatalf("got %v, want a duplicate operationId error", err)
+	}
+}
+
+func TestOperationIDsAcrossRouters(t *testing.T) {
+	ref := InitReflector()
+
+	err := Build(ref, echo.New(),
+		Instance().Prefix("/users").Register(Get[[]file]("", list)),
+		Instance().Prefix("/orders").Register(Get[[]file]("", list)),
+		Instance().Prefix("/files").Register(Get[[]file]("", download).OperationID("list")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"GET /users": "getUsers", "GET /orders": "getOrders", "GET /files": "list"}
+
+	for route, id := range want {
+		if got := operationIDs(ref.Spec)[route]; got != id {
+			t.Errorf("%s: operationId %q, want %q", route, got, id)
+		}
+	}
+}
+
+func TestOperationIDsAcrossBuilds(t *testing.T) {
+	ref := InitReflector()
+	users := Instance().Prefix("/users").Register(Get[[]file]("", list))
+
+	if err := Build(ref, echo.New(), users); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Build(ref, echo.New(), Instance().Prefix("/files").Register(Get[[]file]("", download).OperationID("list"))); err != nil {
+		t.Fatalf("got %v, want the explicit operationId to take over the derived one", err)
+	}
+
+	want := map[string]string{"GET /users": "getUsers", "GET /files": "list"}
+
+	for route, id := range want {
+		if got := operationIDs(ref.Spec)[route]; got != id {
+			t.Errorf("%s: operationId %q, want %q", route, got, id)
+		}
+	}
+
+	if ops := users.Operations(); len(ops) != 1 || ops[0].ID != "getUsers" {
+		t.Errorf("got operations %+v, want getUsers", ops)
+	}
+
+	err := Build(ref, echo.New(), Instance().Prefix("/orders").Register(Get[[]file]("", download).OperationID("list")))
+	if err == nil || !strings.Contains(err.Error(), "duplicate operationId list") {
+		t.Errorf("got %v, want a duplicate operationId error for two explicit operationIds", err)
+	}
+}
+
+func TestConsumes(t *testing.T) {
+	e := echo.New()
+
+	err := Instance().
+		Register(Post[file, file]("/files", download).Consumes(spec.MIMEJSON, spec.MIMEMultipart)).
+		Build(InitReflector(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"accepted", echo.MIMEApplicationJSON, `{}`, http.StatusOK},
+		{"accepted with parameters", echo.MIMEApplicationJSONCharsetUTF8, `{}`, http.StatusOK},
+		{"accepted multipart", echo.MIMEMultipartForm + "; boundary=x", "--x--", http.StatusOK},
+		{"rejected", echo.MIMEApplicationXML, `<file/>`, http.StatusUnsupportedMediaType},
+		{"missing content type", "", `{}`, http.StatusUnsupportedMediaType},
+		{"empty body", echo.MIMEApplicationXML, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("got %d, want %d", rec.Code, tt.code)
+			}
+		})
+	}
+}
+
+type upload struct {
+	Title string                  `form:"title"`
+	File  *multipart.FileHeader   `form:"file"`
+	Files []*multipart.FileHeader `form:"files"`
+}
+
+// multipartBody encodes the fields and a file for each of the given names
+func multipartBody(t *testing.T, fields map[string]string, files ...string) (string, *bytes.Buffer) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, name := range files {
+		part, err := w.CreateFormFile(name, fmt.Sprintf("%s-%d.txt", name, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := part.Write([]byte("content")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.FormDataContentType(), body
+}
+
+func TestBindFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		file  string
+		many  []string
+	}{
+		{"single and many", []string{"file", "files", "files"}, "file-0.txt", []string{"files-1.txt", "files-2.txt"}},
+		{"only many", []string{"files"}, "", []string{"files-0.txt"}},
+		{"unknown field", []string{"other"}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, body := multipartBody(t, map[string]string{"title": "report"}, tt.files...)
+
+			req := httptest.NewRequest(http.MethodPost, "/uploads", body)
+			req.Header.Set(echo.HeaderContentType, contentType)
+
+			var u upload
+
+			if err := bind(echo.New().NewContext(req, httptest.NewRecorder()), &u); err != nil {
+				t.Fatal(err)
+			}
+
+			if u.Title != "report" {
+				t.Errorf("got title %q, want report", u.Title)
+			}
+
+			var file string
+			if u.File != nil {
+				file = u.File.Filename
+			}
+
+			if file != tt.file {
+				t.Errorf("got file %q, want %q", file, tt.file)
+			}
+
+			if len(u.Files) != len(tt.many) {
+				t.Fatalf("got %d files, want %v", len(u.Files), tt.many)
+			}
+
+			for i, f := range u.Files {
+				if f.Filename != tt.many[i] {
+					t.Errorf("got file %s, want %s", f.Filename, tt.many[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBindRaw(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+		got    func(target interface{}) string
+	}{
+		{"bytes", new([]byte), func(target interface{}) string { return string(*target.(*[]byte)) }},
+		{"string", new(string), func(target interface{}) string { return *target.(*string) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Raw bodies are read as is, whatever the content type
+			req := httptest.NewRequest(http.MethodPost, "/raw", strings.NewReader(`{"name":"a"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			if err := bind(echo.New().NewContext(req, httptest.NewRecorder()), tt.target); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tt.got(tt.target); got != `{"name":"a"}` {
+				t.Errorf("got body %q, want the raw request body", got)
+			}
+		})
+	}
+}
+
+type problem struct {
+	Title string `json:"title"`
+}
+
+// mark records that the middleware ran in the X-Chain response header
+func mark(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Add("X-Chain", name)
+
+			return next(c)
+		}
+	}
+}
+
+func TestMountInheritance(t *testing.T) {
+	ref := InitReflector()
+	e := echo.New()
+
+	err := Instance().
+		Prefix("/api").
+		Group("Admin").
+		Tags("admin").
+		Security("bearer", "admin").
+		Use(mark("parent")).
+		Errors(http.StatusForbidden, http.StatusInternalServerError).
+		ErrorBody(problem{}).
+		RateLimit(1, 1).
+		Mount(
+			Instance().Prefix("/users").Tags("users").Use(mark("child")).Register(
+				Get[[]file]("", list, mark("route")),
+				Get[file]("/:id", download).Security("apiKey"),
+			),
+			Instance().Prefix("/public").Group("Public").Security("apiKey").Errors(http.StatusNotFound).Register(
+				Get[[]file]("", list).OperationID("listPublic"),
+			),
+		).
+		Build(ref, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []struct {
+		path     string
+		tags     []string
+		security string
+		errors   []string
+	}{
+		{"/api/users", []string{"Admin", "admin", "users"}, "bearer:admin", []string{"401", "403", "429", "500"}},
+		{"/api/users/{id}", []string{"Admin", "admin", "users"}, "apiKey:", []string{"401", "403", "429", "500"}},
+		{"/api/public", []string{"Public", "admin"}, "apiKey:", []string{"401", "403", "404", "429"}},
+	}
+
+	for _, tt := range operations {
+		t.Run(tt.path, func(t *testing.T) {
+			item, ok := ref.Spec.Paths.MapOfPathItemValues[tt.path]
+			if !ok {
+				t.Fatalf("no path %s in %v", tt.path, operationIDs(ref.Spec))
+			}
+
+			op := item.MapOfOperationValues["get"]
+
+			if strings.Join(op.Tags, ",") != strings.Join(tt.tags, ",") {
+				t.Errorf("got tags %v, want %v", op.Tags, tt.tags)
+			}
+
+			var security []string
+
+			for _, requirement := range op.Security {
+				for name, scopes := range requirement {
+					security = append(security, name+":"+strings.Join(scopes, ","))
+				}
+			}
+
+			if strings.Join(security, " ") != tt.security {
+				t.Errorf("got security %v, want %s", security, tt.security)
+			}
+
+			var errors []string
+
+			for code, res := range op.Responses.MapOfResponseOrRefValues {
+				if strings.HasPrefix(code, "2") {
+					continue
+				}
+
+				errors = append(errors, code)
+
+				if schema := res.Response.Content["application/json"].Schema; schema == nil || schema.SchemaReference == nil ||
+					schema.SchemaReference.Ref != "#/components/schemas/RouterProblem" {
+					t.Errorf("%s response does not document the error body of the parent", code)
+				}
+			}
+
+			sort.Strings(errors)
+
+			if strings.Join(errors, ",") != strings.Join(tt.errors, ",") {
+				t.Errorf("got error responses %v, want %v", errors, tt.errors)
+			}
+		})
+	}
+
+	requests := []struct {
+		name   string
+		path   string
+		remote string
+		code   int
+		chain  []string
+	}{
+		{"parent, child and route middleware in order", "/api/users", "192.0.2.1:1", http.StatusOK, []string{"parent", "child", "route"}},
+		{"parent and child middleware", "/api/users/1", "192.0.2.2:1", http.StatusOK, []string{"parent", "child"}},
+		{"parent middleware", "/api/public", "192.0.2.3:1", http.StatusOK, []string{"parent"}},
+		// The limiter of the parent is shared by the routes of its children
+		{"rate limited", "/api/public", "192.0.2.1:1", http.StatusTooManyRequests, nil},
+	}
+
+	for _, tt := range requests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remote
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("got %d, want %d", rec.Code, tt.code)
+			}
+
+			if tt.chain == nil {
+				return
+			}
+
+			if got := rec.Header().Values("X-Chain"); strings.Join(got, ",") != strings.Join(tt.chain, ",") {
+				t.Errorf("middleware ran as %v, want %v", got, tt.chain)
+			}
+		})
+	}
+}
+
+func TestExample(t *testing.T) {
+	ref := InitReflector()
+
+	err := Instance().
+		Register(Post[file, file]("/files", download).Example("report", file{Name: "report.pdf"})).
+		Build(ref, echo.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := operation(ref.Spec, http.MethodPost, "/files")
+	if op == nil || op.RequestBody == nil || op.RequestBody.RequestBody == nil {
+		t.Fatal("no request body")
+	}
+
+	example, ok := op.RequestBody.RequestBody.Content[spec.MIMEJSON].Examples["report"]
+	if !ok || example.Example == nil || example.Example.Value == nil {
+		t.Fatal("request body has no example report")
+	}
+
+	if got, ok := (*example.Example.Value).(file); !ok || got.Name != "report.pdf" {
+		t.Errorf("got example %v, want the report file", *example.Example.Value)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   *Route
+		methods []string
+	}{
+		{
+			name:    "any",
+			route:   Any[file, file]("/files", download),
+			methods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace},
+		},
+		{
+			name:    "match",
+			route:   Match[file, file]([]string{"get", http.MethodPut}, "/files", download),
+			methods: []string{http.MethodGet, http.MethodPut},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := InitReflector()
+			e := echo.New()
+
+			if err := Instance().Register(tt.route).Build(ref, e); err != nil {
+				t.Fatal(err)
+			}
+
+			if item := ref.Spec.Paths.MapOfPathItemValues["/files"]; len(item.MapOfOperationValues) != len(tt.methods) {
+				t.Errorf("documented %d operations, want %v", len(item.MapOfOperationValues), tt.methods)
+			}
+
+			for _, method := range tt.methods {
+				if operation(ref.Spec, method, "/files") == nil {
+					t.Errorf("%s /files is not documented", method)
+				}
+
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(method, "/files", nil))
+
+				if rec.Code != http.StatusOK {
+					t.Errorf("%s /files: got %d, want %d", method, rec.Code, http.StatusOK)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsupportedMethod(t *testing.T) {
+	err := Instance().
+		Register(Match[file, file]([]string{http.MethodGet, http.MethodConnect}, "/files", download)).
+		Build(InitReflector(), echo.New())
+
+	if err == nil || !strings.Contains(err.Error(), "unsupported method CONNECT") {
+		t.Fatalf("got %v, want an unsupported method error", err)
+	}
+}
+
+type store[T any] struct{}
+
+func (s *store[T]) List(c echo.Context) error {
+	return c.JSON(http.StatusOK, []T{})
+}
+
+func handle[T any](c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func TestGenericOperationIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		want    string
+	}{
+		{"method of a generic type", (&store[file]{}).List, "storeList"},
+		{"generic function", handle[file], "handle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := operationID(tt.handler); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountTwice(t *testing.T) {
+	t.Run("under two prefixes", func(t *testing.T) {
+		ref := InitReflector()
+		e := echo.New()
+		users := Instance().Prefix("/users").Tags("users").Register(Get[file]("/:id", download))
+
+		err := Build(ref, e,
+			Instance().Prefix("/v1").Tags("v1").Mount(users),
+			Instance().Prefix("/v2").Tags("v2").Mount(users),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for path, tags := range map[string]string{"/v1/users/{id}": "v1,users", "/v2/users/{id}": "v2,users"} {
+			op := operation(ref.Spec, http.MethodGet, path)
+			if op == nil {
+				t.Errorf("no GET %s in %v", path, operationIDs(ref.Spec))
+
+				continue
+			}
+
+			// The first tag is the package of the handler
+			if got := strings.Join(op.Tags, ","); !strings.HasSuffix(got, ","+tags) {
+				t.Errorf("%s: got tags %s, want them to end with %s", path, got, tags)
+			}
+		}
+
+		if n := len(ref.Spec.Paths.MapOfPathItemValues); n != 2 {
+			t.Errorf("documented %d paths, want 2: %v", n, operationIDs(ref.Spec))
+		}
+
+		for _, path := range []string{"/v1/users/1", "/v2/users/1"} {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET %s: got %d, want %d", path, rec.Code, http.StatusOK)
+			}
+		}
+
+		var paths []string
+
+		for _, op := range users.Operations() {
+			paths = append(paths, op.Path)
+		}
+
+		sort.Strings(paths)
+
+		if strings.Join(paths, ",") != "/v1/users/{id},/v2/users/{id}" {
+			t.Errorf("got operations at %v, want one per mount", paths)
+		}
+	})
+
+	t.Run("under two versions", func(t *testing.T) {
+		versioning := HeaderVersioning("X-API-Version")
+		v1, v2 := versioning.Version("v1"), versioning.Version("v2")
+		users := Instance().Prefix("/users").Register(Get[file]("/:id", download).OperationID("getUser"))
+
+		err := Build(InitReflector(), echo.New(),
+			Instance().Version(v1).Mount(users),
+			Instance().Version(v2).Mount(users),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, v := range []*Version{v1, v2} {
+			op := operation(v.Reflector().Spec, http.MethodGet, "/users/{id}")
+			if op == nil {
+				t.Errorf("%s: no GET /users/{id} in %v", v.Name(), operationIDs(v.Reflector().Spec))
+
+				continue
+			}
+
+			headers := 0
+
+			for _, p := range op.Parameters {
+				if p.Parameter != nil && p.Parameter.Name == "X-API-Version" {
+					headers++
+				}
+			}
+
+			if headers != 1 {
+				t.Errorf("%s: documents the version header %d times, want once", v.Name(), headers)
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"runtime"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+
+	"github.com/khvh/gwf/pkg/queue"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// TaskStatus creates a GET route returning the state of a task enqueued from task and its result decoded as R,
+// e.g. TaskStatus[Export]("/exports/:id", ExportTask). Path must have an :id param. It responds 404 for tasks of other
+// types and once the task is deleted, keep it with asynq.Retention.
+func TaskStatus[R, P interface{}](path string, task *queue.Task[P], handlers ...echo.MiddlewareFunc) *Route {
+	pc, _, _, _ := runtime.Caller(1)
+
+	return statusRoute[R](path, getPackage(pc), task.Name(), handlers)
+}
+
+func statusRoute[R interface{}](path, pkg, typename string, handlers []echo.MiddlewareFunc) *Route {
+	return &Route{
+		path:        path,
+		spec:        spec.Of(path, pkg).Get(queue.TaskStatus[R]{}),
+		methods:     []string{http.MethodGet},
+		suggestedID: statusOperationID[R](),
+		handler:     taskStatus[R](typename),
+		mw:          handlers,
+	}
+}
+
+func taskStatus[R interface{}](typename string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		info, err := queue.Inspect(c.Request().Context(), c.Param("id"))
+
+		// The IDs of other tasks are not found either, their results are not of type R
+		if errors.Is(err, asynq.ErrTaskNotFound) || (err == nil && info.Type != typename) {
+			return c.JSON(http.StatusNotFound, spec.Err("not_found").Message("task not found"))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		status, err := queue.Status[R](info)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, status)
+	}
+}
+
+// statusOperationID returns e.g. getExportStatus for R Export
+func statusOperationID[R interface{}]() string {
+	t := reflect.TypeOf((*R)(nil)).Elem()
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Name() == "" {
+		return "getTaskStatus"
+	}
+
+	return "get" + genericDefName(t, t.Name()) + "Status"
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+
+	"github.com/khvh/gwf/pkg/queue"
+)
+
+func TestTaskStatus(t *testing.T) {
+	defer queue.SetDefaultClient(nil)
+
+	exportTask := queue.NewTask[string]("test:export", asynq.Retention(time.Hour))
+	otherTask := queue.NewTask[string]("test:other", asynq.Retention(time.Hour))
+
+	handle := func(_ context.Context, name string) (file, error) {
+		return file{Name: name}, nil
+	}
+
+	m := queue.NewMemory(queue.Synchronous())
+	q := queue.New(m).Register(queue.HandleResult(exportTask, handle), queue.HandleResult(otherTask, handle))
+
+	q.Run()
+	defer q.Shutdown()
+
+	ctx := context.Background()
+
+	export, err := exportTask.Enqueue(ctx, "export.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := otherTask.Enqueue(ctx, "other.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		code   int
+		result string
+	}{
+		{"task of the route", export.ID, http.StatusOK, "export.csv"},
+		{"task of another type", other.ID, http.StatusNotFound, ""},
+		{"unknown task", "unknown", http.StatusNotFound, ""},
+	}
+
+	route := TaskStatus[file]("/exports/:id", exportTask)
+	e := echo.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/exports/"+tt.id, nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			if err := route.handler(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.code {
+				t.Fatalf("got %d, want %d", rec.Code, tt.code)
+			}
+
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var status queue.TaskStatus[file]
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+				t.Fatal(err)
+			}
+
+			if status.Result == nil || status.Result.Name != tt.result {
+				t.Errorf("got result %v, want %s", status.Result, tt.result)
+			}
+		})
+	}
+}
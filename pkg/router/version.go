@@ -0,0 +1,275 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// Strategy describes how a client selects an API version
+type Strategy int
+
+const (
+	// PathStrategy selects the version with a path segment, e.g. /api/v1/users
+	PathStrategy Strategy = iota
+	// HeaderStrategy selects the version with a request header, e.g. X-API-Version: v1
+	HeaderStrategy
+	// MediaTypeStrategy selects the version with a vendor media type, e.g. Accept: application/vnd.example.v1+json
+	MediaTypeStrategy
+)
+
+// Versioning holds the API versions of an application and how they are selected
+type Versioning struct {
+	strategy Strategy
+	prefix   string
+	header   string
+	vendor   string
+	def      string
+	versions []*Version
+	dispatch map[string]*dispatcher
+}
+
+// Version is a single API version with its own OpenAPI document
+type Version struct {
+	name       string
+	versioning *Versioning
+	ref        *openapi3.Reflector
+	deprecated bool
+	since      time.Time
+	sunset     time.Time
+	link       string
+}
+
+// PathVersioning selects versions by a path segment placed after prefix
+func PathVersioning(prefix string) *Versioning {
+	return newVersioning(PathStrategy, func(v *Versioning) {
+		v.prefix = prefix
+	})
+}
+
+// HeaderVersioning selects versions by the value of a request header
+func HeaderVersioning(header string) *Versioning {
+	return newVersioning(HeaderStrategy, func(v *Versioning) {
+		v.header = header
+	})
+}
+
+// MediaTypeVersioning selects versions by an Accept header of the form application/vnd.<vendor>.<version>+json
+func MediaTypeVersioning(vendor string) *Versioning {
+	return newVersioning(MediaTypeStrategy, func(v *Versioning) {
+		v.vendor = vendor
+	})
+}
+
+func newVersioning(strategy Strategy, fn func(v *Versioning)) *Versioning {
+	v := &Versioning{
+		strategy: strategy,
+		dispatch: map[string]*dispatcher{},
+	}
+
+	fn(v)
+
+	return v
+}
+
+// Version returns the version with name, creating it if needed
+func (v *Versioning) Version(name string) *Version {
+	for _, version := range v.versions {
+		if version.name == name {
+			return version
+		}
+	}
+
+	version := &Version{
+		name:       name,
+		versioning: v,
+	}
+
+	v.versions = append(v.versions, version)
+
+	return version
+}
+
+// Default sets the version used when a request does not select one, the first version by default
+func (v *Versioning) Default(name string) *Versioning {
+	v.def = name
+
+	return v
+}
+
+// Versions returns all declared versions
+func (v *Versioning) Versions() []*Version {
+	return v.versions
+}
+
+// DefaultVersion returns the name of the version used when a request does not select one
+func (v *Versioning) DefaultVersion() string {
+	if v.def == "" && len(v.versions) > 0 {
+		return v.versions[0].name
+	}
+
+	return v.def
+}
+
+// Resolve returns the name of the version a request selects, the default version when it selects none
+func (v *Versioning) Resolve(req *http.Request) string {
+	return v.resolve(req)
+}
+
+func (v *Versioning) resolve(req *http.Request) string {
+	switch v.strategy {
+	case PathStrategy:
+		prefix := strings.TrimSuffix(v.prefix, "/") + "/"
+
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			name := strings.Split(strings.TrimPrefix(req.URL.Path, prefix), "/")[0]
+
+			for _, version := range v.versions {
+				if version.name == name {
+					return name
+				}
+			}
+		}
+	case HeaderStrategy:
+		if name := req.Header.Get(v.header); name != "" {
+			return name
+		}
+	case MediaTypeStrategy:
+		prefix := "application/vnd." + v.vendor + "."
+
+		for _, accept := range strings.Split(req.Header.Get(echo.HeaderAccept), ",") {
+			mediaType := strings.TrimSpace(strings.Split(accept, ";")[0])
+
+			if strings.HasPrefix(mediaType, prefix) {
+				return strings.Split(strings.TrimPrefix(mediaType, prefix), "+")[0]
+			}
+		}
+	}
+
+	return v.DefaultVersion()
+}
+
+// Deprecate marks the version as deprecated, its responses advertise the deprecation, the sunset date and the successor link.
+// Calls are counted by the deprecated_route_calls_total metric, per client only for the clients given to TrackClients.
+func (v *Version) Deprecate(sunset time.Time, link string) *Version {
+	v.deprecated = true
+	v.sunset = sunset
+	v.link = link
+
+	v.Reflector().Spec.WithMapOfAnythingItem("x-deprecated", true)
+
+	if !sunset.IsZero() {
+		v.Reflector().Spec.WithMapOfAnythingItem("x-sunset", sunset.UTC().Format(time.RFC3339))
+	}
+
+	return v
+}
+
+// DeprecatedSince marks the version as deprecated since the given date, advertised by the Deprecation header
+func (v *Version) DeprecatedSince(since time.Time) *Version {
+	v.since = since
+
+	return v.Deprecate(v.sunset, v.link)
+}
+
+// Name returns the version name
+func (v *Version) Name() string {
+	return v.name
+}
+
+// Deprecated reports whether the version is deprecated
+func (v *Version) Deprecated() bool {
+	return v.deprecated
+}
+
+// Since returns the date the version was deprecated
+func (v *Version) Since() time.Time {
+	return v.since
+}
+
+// Sunset returns the date the version will be removed
+func (v *Version) Sunset() time.Time {
+	return v.sunset
+}
+
+// Reflector returns the reflector holding the OpenAPI document of the version
+func (v *Version) Reflector() *openapi3.Reflector {
+	if v.ref == nil {
+		v.ref = InitReflector()
+		v.ref.Spec.Info.WithVersion(v.name)
+	}
+
+	return v.ref
+}
+
+func (v *Version) prefix(prefix string) string {
+	if v.versioning.strategy != PathStrategy {
+		return prefix
+	}
+
+	return joinPrefix(joinPrefix(v.versioning.prefix, "/"+v.name), prefix)
+}
+
+func (v *Version) middleware() []echo.MiddlewareFunc {
+	if !v.deprecated {
+		return nil
+	}
+
+	return []echo.MiddlewareFunc{deprecation(&deprecated{since: v.since, sunset: v.sunset, link: v.link})}
+}
+
+// dispatcher serves every version of a single method and path when the version is not part of the path
+type dispatcher struct {
+	versioning *Versioning
+	handlers   map[string]echo.HandlerFunc
+}
+
+func (v *Versioning) register(group *echo.Group, method, path, key string, version *Version, handler echo.HandlerFunc) {
+	d, ok := v.dispatch[key]
+
+	if !ok {
+		d = &dispatcher{
+			versioning: v,
+			handlers:   map[string]echo.HandlerFunc{},
+		}
+
+		v.dispatch[key] = d
+
+		group.Add(method, path, d.handle)
+	}
+
+	d.handlers[version.name] = handler
+}
+
+func (d *dispatcher) handle(c echo.Context) error {
+	name := d.versioning.resolve(c.Request())
+
+	if d.versioning.strategy == HeaderStrategy {
+		c.Response().Header().Add(echo.HeaderVary, d.versioning.header)
+	} else {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	}
+
+	handler, ok := d.handlers[name]
+	if !ok {
+		if d.versioning.strategy == MediaTypeStrategy {
+			return echo.NewHTTPError(http.StatusNotAcceptable, fmt.Sprintf("unsupported API version %s", name))
+		}
+
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unsupported API version %s", name))
+	}
+
+	return handler(c)
+}
+
+func applyMiddleware(handler echo.HandlerFunc, mw ...echo.MiddlewareFunc) echo.HandlerFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i](handler)
+	}
+
+	return handler
+}
@@ -0,0 +1,245 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+func respond(name string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.String(http.StatusOK, name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning *Versioning
+		path       string
+		header     map[string]string
+		want       string
+	}{
+		{"path", PathVersioning("/api"), "/api/v2/users", nil, "v2"},
+		{"path without a version", PathVersioning("/api"), "/api/users", nil, "v1"},
+		{"path with an undeclared version", PathVersioning("/api"), "/api/v3/users", nil, "v1"},
+		{"path outside the prefix", PathVersioning("/api"), "/v2/users", nil, "v1"},
+		{"header", HeaderVersioning("X-API-Version"), "/users", map[string]string{"X-API-Version": "v2"}, "v2"},
+		{"header missing", HeaderVersioning("X-API-Version"), "/users", nil, "v1"},
+		{"header with an undeclared version", HeaderVersioning("X-API-Version"), "/users", map[string]string{"X-API-Version": "v3"}, "v3"},
+		{"media type", MediaTypeVersioning("example"), "/users", map[string]string{"Accept": "application/vnd.example.v2+json"}, "v2"},
+		{"media type among others", MediaTypeVersioning("example"), "/users", map[string]string{"Accept": "text/html, application/vnd.example.v2+json;q=0.9"}, "v2"},
+		{"media type of another vendor", MediaTypeVersioning("example"), "/users", map[string]string{"Accept": "application/vnd.other.v2+json"}, "v1"},
+		{"explicit default", HeaderVersioning("X-API-Version").Default("v2"), "/users", nil, "v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.versioning.Version("v1")
+			tt.versioning.Version("v2")
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			if got := tt.versioning.Resolve(req); got != tt.want {
+				t.Errorf("resolved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionRouting(t *testing.T) {
+	tests := []struct {
+		name       string
+		versioning func() *Versioning
+		path       string
+		header     map[string]string
+		code       int
+		handler    string
+	}{
+		{
+			name:       "path v1",
+			versioning: func() *Versioning { return PathVersioning("/api") },
+			path:       "/api/v1/users",
+			code:       http.StatusOK,
+			handler:    "v1",
+		},
+		{
+			name:       "path v2",
+			versioning: func() *Versioning { return PathVersioning("/api") },
+			path:       "/api/v2/users",
+			code:       http.StatusOK,
+			handler:    "v2",
+		},
+		{
+			name:       "path without a version",
+			versioning: func() *Versioning { return PathVersioning("/api") },
+			path:       "/users",
+			code:       http.StatusNotFound,
+		},
+		{
+			name:       "header",
+			versioning: func() *Versioning { return HeaderVersioning("X-API-Version") },
+			path:       "/users",
+			header:     map[string]string{"X-API-Version": "v2"},
+			code:       http.StatusOK,
+			handler:    "v2",
+		},
+		{
+			name:       "header default",
+			versioning: func() *Versioning { return HeaderVersioning("X-API-Version") },
+			path:       "/users",
+			code:       http.StatusOK,
+			handler:    "v1",
+		},
+		{
+			name:       "header with an undeclared version",
+			versioning: func() *Versioning { return HeaderVersioning("X-API-Version") },
+			path:       "/users",
+			header:     map[string]string{"X-API-Version": "v3"},
+			code:       http.StatusBadRequest,
+		},
+		{
+			name:       "media type",
+			versioning: func() *Versioning { return MediaTypeVersioning("example") },
+			path:       "/users",
+			header:     map[string]string{"Accept": "application/vnd.example.v2+json"},
+			code:       http.StatusOK,
+			handler:    "v2",
+		},
+		{
+			name:       "media type with an undeclared version",
+			versioning: func() *Versioning { return MediaTypeVersioning("example") },
+			path:       "/users",
+			header:     map[string]string{"Accept": "application/vnd.example.v3+json"},
+			code:       http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versioning := tt.versioning()
+			ref := InitReflector()
+			e := echo.New()
+
+			err := Instance().Mount(
+				Instance().Version(versioning.Version("v1")).Register(Get[[]file]("/users", respond("v1"))),
+				Instance().Version(versioning.Version("v2")).Register(Get[[]file]("/users", respond("v2"))),
+			).Build(ref, e)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+
+			if tt.handler != "" && rec.Body.String() != tt.handler {
+				t.Errorf("served by the %s handler, want %s", rec.Body, tt.handler)
+			}
+
+			if len(ref.Spec.Paths.MapOfPathItemValues) != 0 {
+				t.Errorf("the unversioned document has paths %v, want none", operationIDs(ref.Spec))
+			}
+
+			for _, version := range versioning.Versions() {
+				path := "/users"
+				if versioning.strategy == PathStrategy {
+					path = "/api/" + version.Name() + "/users"
+				}
+
+				if _, ok := version.Reflector().Spec.Paths.MapOfPathItemValues[path]; !ok || len(version.Reflector().Spec.Paths.MapOfPathItemValues) != 1 {
+					t.Errorf("the %s document has paths %v, want only %s", version.Name(), operationIDs(version.Reflector().Spec), path)
+				}
+			}
+		})
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	sunset := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		sunset time.Time
+		want   map[string]interface{}
+	}{
+		{"with a sunset date", sunset, map[string]interface{}{"x-deprecated": true, "x-sunset": "2030-01-02T03:04:05Z"}},
+		{"without a sunset date", time.Time{}, map[string]interface{}{"x-deprecated": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versioning := HeaderVersioning("X-API-Version")
+			v1 := versioning.Version("v1").Deprecate(tt.sunset, "/docs/v2")
+			v2 := versioning.Version("v2")
+
+			err := Instance().Mount(
+				Instance().Version(v1).Register(Get[[]file]("/users", respond("v1"))),
+				Instance().Version(v2).Register(Get[[]file]("/users", respond("v2"))),
+			).Build(InitReflector(), echo.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !v1.Deprecated() || !v1.Sunset().Equal(tt.sunset) {
+				t.Errorf("deprecated %v with sunset %s, want true with %s", v1.Deprecated(), v1.Sunset(), tt.sunset)
+			}
+
+			extensions := v1.Reflector().Spec.MapOfAnything
+
+			if len(extensions) != len(tt.want) {
+				t.Errorf("got extensions %v, want %v", extensions, tt.want)
+			}
+
+			for k, v := range tt.want {
+				if extensions[k] != v {
+					t.Errorf("got %s %v, want %v", k, extensions[k], v)
+				}
+			}
+
+			if op := operation(v1.Reflector().Spec, http.MethodGet, "/users"); op == nil || op.Deprecated == nil || !*op.Deprecated {
+				t.Error("the v1 operation is not deprecated")
+			}
+
+			if len(v2.Reflector().Spec.MapOfAnything) != 0 {
+				t.Errorf("the v2 document has extensions %v, want none", v2.Reflector().Spec.MapOfAnything)
+			}
+
+			if op := operation(v2.Reflector().Spec, http.MethodGet, "/users"); op == nil || op.Deprecated != nil && *op.Deprecated {
+				t.Error("the v2 operation is deprecated")
+			}
+		})
+	}
+}
+
+func operation(s *openapi3.Spec, method, path string) *openapi3.Operation {
+	item, ok := s.Paths.MapOfPathItemValues[path]
+	if !ok {
+		return nil
+	}
+
+	op, ok := item.MapOfOperationValues[strings.ToLower(method)]
+	if !ok {
+		return nil
+	}
+
+	return &op
+}
@@ -0,0 +1,192 @@
+package spec
+
+import (
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/swaggest/jsonschema-go"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// Content types that can be used for request and response bodies
+const (
+	MIMEJSON        = "application/json"
+	MIMEXML         = "application/xml"
+	MIMEForm        = "application/x-www-form-urlencoded"
+	MIMEMultipart   = "multipart/form-data"
+	MIMEOctetStream = "application/octet-stream"
+	MIMECSV         = "text/csv"
+)
+
+// AddConsumes sets the content types accepted for the request body, JSON by default
+func (o *OAS) AddConsumes(types ...string) *OAS {
+	o.consumes = append(o.consumes, types...)
+
+	return o
+}
+
+// AddProduces sets the content types of successful responses, JSON by default
+func (o *OAS) AddProduces(types ...string) *OAS {
+	o.produces = append(o.produces, types...)
+
+	return o
+}
+
+func (o *OAS) setRequest(ref *openapi3.Reflector, op *openapi3.Operation, method string) error {
+	consumes := o.consumes
+	if len(consumes) == 0 {
+		consumes = []string{MIMEJSON}
+	}
+
+	for _, mime := range consumes {
+		if mime == MIMEJSON {
+			if err := ref.SetRequest(op, o.in, method); err != nil {
+				return err
+			}
+
+			// SetRequest only documents fields with json tags, the body is decoded by field name without them
+			if o.in != nil && !hasContent(op.RequestBody, MIMEJSON) {
+				schema, err := contentSchema(ref, o.in, MIMEJSON, jsonschema.ProcessWithoutTags)
+				if err != nil {
+					return err
+				}
+
+				op.RequestBodyEns().RequestBodyEns().WithContentItem(MIMEJSON, openapi3.MediaType{Schema: schema})
+			}
+
+			continue
+		}
+
+		schema, err := contentSchema(ref, o.in, mime)
+		if err != nil {
+			return err
+		}
+
+		op.RequestBodyEns().RequestBodyEns().WithContentItem(mime, openapi3.MediaType{Schema: schema})
+	}
+
+	return nil
+}
+
+func (o *OAS) setResponse(ref *openapi3.Reflector, op *openapi3.Operation, response *apiResponse) error {
+	if response.noContent {
+		return ref.SetupResponse(openapi3.OperationContext{
+			Operation:  op,
+			HTTPStatus: response.code,
+		})
+	}
+
+	produces := o.produces
+	if len(produces) == 0 || response.code >= http.StatusBadRequest {
+		produces = []string{MIMEJSON}
+	}
+
+	for _, mime := range produces {
+		if mime == MIMEJSON {
+			if err := ref.SetJSONResponse(op, response.body, response.code); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		schema, err := contentSchema(ref, response.body, mime)
+		if err != nil {
+			return err
+		}
+
+		code := strconv.Itoa(response.code)
+		res := op.Responses.MapOfResponseOrRefValues[code]
+
+		if res.Response == nil {
+			res.Response = &openapi3.Response{Description: http.StatusText(response.code)}
+		}
+
+		res.Response.WithContentItem(mime, openapi3.MediaType{Schema: schema})
+		op.Responses.WithMapOfResponseOrRefValuesItem(code, res)
+	}
+
+	return nil
+}
+
+// hasContent reports whether body documents the content type
+func hasContent(body *openapi3.RequestBodyOrRef, mime string) bool {
+	if body == nil || body.RequestBody == nil {
+		return false
+	}
+
+	_, ok := body.RequestBody.Content[mime]
+
+	return ok
+}
+
+// contentSchema reflects the schema of value as sent with the given content type
+func contentSchema(ref *openapi3.Reflector, value interface{}, mime string, opts ...func(rc *jsonschema.ReflectContext)) (*openapi3.SchemaOrRef, error) {
+	switch mime {
+	case MIMEOctetStream:
+		return stringSchema("binary"), nil
+	case MIMECSV:
+		return stringSchema(""), nil
+	}
+
+	if value == nil {
+		return stringSchema(""), nil
+	}
+
+	tag, prefix := "json", ""
+
+	switch mime {
+	case MIMEForm, MIMEMultipart:
+		tag, prefix = "form", "Form"
+	case MIMEXML:
+		tag, prefix = "xml", "Xml"
+	}
+
+	schema, err := ref.Reflect(value, append([]func(rc *jsonschema.ReflectContext){
+		jsonschema.RootRef,
+		jsonschema.DefinitionsPrefix("#/components/schemas/" + prefix),
+		jsonschema.PropertyNameTag(tag),
+		jsonschema.InterceptType(func(v reflect.Value, s *jsonschema.Schema) (bool, error) {
+			switch v.Interface().(type) {
+			case *multipart.FileHeader, *multipart.File:
+				s.AddType(jsonschema.String)
+				s.WithFormat("binary")
+
+				return true, nil
+			}
+
+			return false, nil
+		}),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, def := range schema.Definitions {
+		s := openapi3.SchemaOrRef{}
+
+		s.FromJSONSchema(def)
+
+		ref.SpecEns().ComponentsEns().SchemasEns().WithMapOfSchemaOrRefValuesItem(prefix+name, s)
+	}
+
+	s := openapi3.SchemaOrRef{}
+
+	s.FromJSONSchema(schema.ToSchemaOrBool())
+
+	return &s, nil
+}
+
+func stringSchema(format string) *openapi3.SchemaOrRef {
+	schema := openapi3.Schema{}
+
+	schema.WithType(openapi3.SchemaTypeString)
+
+	if format != "" {
+		schema.WithFormat(format)
+	}
+
+	return &openapi3.SchemaOrRef{Schema: &schema}
+}
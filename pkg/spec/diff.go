@@ -0,0 +1,527 @@
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// ChangeKind tells whether a change breaks existing clients
+type ChangeKind string
+
+const (
+	// Breaking changes break existing clients
+	Breaking ChangeKind = "breaking"
+	// NonBreaking changes are safe for existing clients
+	NonBreaking ChangeKind = "non-breaking"
+)
+
+// Change is a single difference between two specs
+type Change struct {
+	Kind      ChangeKind `json:"kind"`
+	Operation string     `json:"operation"`
+	Message   string     `json:"message"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("[%s] %s: %s", c.Kind, c.Operation, c.Message)
+}
+
+// Changes is a list of changes between two specs
+type Changes []Change
+
+// Breaking returns the breaking changes
+func (c Changes) Breaking() Changes {
+	breaking := Changes{}
+
+	for _, change := range c {
+		if change.Kind == Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+
+	return breaking
+}
+
+// HasBreaking reports whether any change is breaking
+func (c Changes) HasBreaking() bool {
+	return len(c.Breaking()) > 0
+}
+
+// LoadFile reads a json or yaml spec from path
+func LoadFile(path string) (*openapi3.Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &openapi3.Spec{}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		err = s.UnmarshalYAML(b)
+	} else {
+		err = s.UnmarshalJSON(b)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// direction tells whether a schema is sent by the client or returned to it, which decides what breaks
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+type differ struct {
+	old, new *openapi3.Spec
+	op       string
+	changes  Changes
+}
+
+// Diff compares two specs and reports the changes from old to new, e.g. removed operations, new required
+// params, narrowed enums and changed response types are breaking
+func Diff(old, new *openapi3.Spec) Changes {
+	d := &differ{old: old, new: new}
+
+	oldOps, newOps := operations(old), operations(new)
+
+	for _, key := range sortedKeys(oldOps) {
+		d.op = oldOps[key].name
+
+		newOp, ok := newOps[key]
+		if !ok {
+			d.add(Breaking, "operation removed")
+
+			continue
+		}
+
+		d.op = newOp.name
+		d.operation(oldOps[key].op, newOp.op)
+	}
+
+	for _, key := range sortedKeys(newOps) {
+		if _, ok := oldOps[key]; !ok {
+			d.op = newOps[key].name
+			d.add(NonBreaking, "operation added")
+		}
+	}
+
+	return d.changes
+}
+
+func (d *differ) add(kind ChangeKind, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:      kind,
+		Operation: d.op,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) operation(old, new *openapi3.Operation) {
+	if old.ID != nil && new.ID != nil && *old.ID != *new.ID {
+		d.add(Breaking, "operationId changed from %s to %s", *old.ID, *new.ID)
+	}
+
+	if !isTrue(old.Deprecated) && isTrue(new.Deprecated) {
+		d.add(NonBreaking, "operation deprecated")
+	}
+
+	d.params(old, new)
+	d.requestBody(old.RequestBody, new.RequestBody)
+	d.responses(old.Responses, new.Responses)
+}
+
+func (d *differ) params(old, new *openapi3.Operation) {
+	oldParams, newParams := params(old), params(new)
+
+	for _, key := range sortedKeys(oldParams) {
+		oldParam := oldParams[key]
+
+		newParam, ok := newParams[key]
+		if !ok {
+			d.add(NonBreaking, "%s removed", key)
+
+			continue
+		}
+
+		if !isTrue(oldParam.Required) && isTrue(newParam.Required) {
+			d.add(Breaking, "%s became required", key)
+		}
+
+		if isTrue(oldParam.Required) && !isTrue(newParam.Required) {
+			d.add(NonBreaking, "%s became optional", key)
+		}
+
+		d.schema(key, request, oldParam.Schema, newParam.Schema, map[string]bool{})
+	}
+
+	for _, key := range sortedKeys(newParams) {
+		if _, ok := oldParams[key]; ok {
+			continue
+		}
+
+		if isTrue(newParams[key].Required) {
+			d.add(Breaking, "required %s added", key)
+		} else {
+			d.add(NonBreaking, "optional %s added", key)
+		}
+	}
+}
+
+func (d *differ) requestBody(old, new *openapi3.RequestBodyOrRef) {
+	var oldBody, newBody *openapi3.RequestBody
+
+	if old != nil {
+		oldBody = old.RequestBody
+	}
+
+	if new != nil {
+		newBody = new.RequestBody
+	}
+
+	switch {
+	case oldBody == nil && newBody == nil:
+		return
+	case oldBody == nil:
+		if isTrue(newBody.Required) {
+			d.add(Breaking, "required request body added")
+		} else {
+			d.add(NonBreaking, "optional request body added")
+		}
+
+		return
+	case newBody == nil:
+		d.add(NonBreaking, "request body removed")
+
+		return
+	}
+
+	if !isTrue(oldBody.Required) && isTrue(newBody.Required) {
+		d.add(Breaking, "request body became required")
+	}
+
+	d.content("request body", request, oldBody.Content, newBody.Content)
+}
+
+func (d *differ) responses(old, new openapi3.Responses) {
+	for _, code := range sortedKeys(old.MapOfResponseOrRefValues) {
+		oldRes := old.MapOfResponseOrRefValues[code].Response
+
+		newRes, ok := new.MapOfResponseOrRefValues[code]
+		if !ok {
+			if strings.HasPrefix(code, "2") {
+				d.add(Breaking, "response %s removed", code)
+			} else {
+				d.add(NonBreaking, "response %s removed", code)
+			}
+
+			continue
+		}
+
+		if oldRes == nil || newRes.Response == nil {
+			continue
+		}
+
+		d.content("response "+code, response, oldRes.Content, newRes.Response.Content)
+	}
+
+	for _, code := range sortedKeys(new.MapOfResponseOrRefValues) {
+		if _, ok := old.MapOfResponseOrRefValues[code]; !ok {
+			d.add(NonBreaking, "response %s added", code)
+		}
+	}
+}
+
+func (d *differ) content(name string, dir direction, old, new map[string]openapi3.MediaType) {
+	for _, mediaType := range sortedKeys(old) {
+		newMedia, ok := new[mediaType]
+		if !ok {
+			d.add(Breaking, "%s no longer supports %s", name, mediaType)
+
+			continue
+		}
+
+		d.schema(name, dir, old[mediaType].Schema, newMedia.Schema, map[string]bool{})
+	}
+
+	for _, mediaType := range sortedKeys(new) {
+		if _, ok := old[mediaType]; !ok {
+			d.add(NonBreaking, "%s supports %s", name, mediaType)
+		}
+	}
+}
+
+// schema compares two schemas, seen guards against recursive definitions
+func (d *differ) schema(name string, dir direction, oldRef, newRef *openapi3.SchemaOrRef, seen map[string]bool) {
+	if oldRef != nil && oldRef.SchemaReference != nil && newRef != nil && newRef.SchemaReference != nil {
+		key := oldRef.SchemaReference.Ref + "|" + newRef.SchemaReference.Ref
+
+		if seen[key] {
+			return
+		}
+
+		seen[key] = true
+	}
+
+	old, new := resolve(d.old, oldRef), resolve(d.new, newRef)
+
+	if old == nil || new == nil {
+		return
+	}
+
+	if oldType, newType := schemaType(old), schemaType(new); oldType != newType {
+		d.add(Breaking, "%s type changed from %s to %s", name, oldType, newType)
+
+		return
+	}
+
+	if oldFormat, newFormat := str(old.Format), str(new.Format); oldFormat != newFormat {
+		d.add(Breaking, "%s format changed from %s to %s", name, oldFormat, newFormat)
+	}
+
+	d.enum(name, dir, old.Enum, new.Enum)
+
+	if old.Items != nil || new.Items != nil {
+		d.schema(name+"[]", dir, old.Items, new.Items, seen)
+	}
+
+	d.properties(name, dir, old, new, seen)
+}
+
+func (d *differ) enum(name string, dir direction, old, new []interface{}) {
+	if len(old) == 0 && len(new) == 0 {
+		return
+	}
+
+	// An enum that appears restricts the values, one that disappears allows anything
+	removed, added := missing(old, new), missing(new, old)
+
+	if len(old) == 0 {
+		removed, added = []interface{}{"any value"}, nil
+	}
+
+	if len(new) == 0 {
+		removed, added = nil, []interface{}{"any value"}
+	}
+
+	// Clients may send fewer values but must handle every value that is returned
+	if len(removed) > 0 {
+		kind := Breaking
+		if dir == response {
+			kind = NonBreaking
+		}
+
+		d.add(kind, "%s enum narrowed, removed %v", name, removed)
+	}
+
+	if len(added) > 0 {
+		kind := NonBreaking
+		if dir == response {
+			kind = Breaking
+		}
+
+		d.add(kind, "%s enum widened, added %v", name, added)
+	}
+}
+
+func (d *differ) properties(name string, dir direction, old, new *openapi3.Schema, seen map[string]bool) {
+	oldRequired, newRequired := set(old.Required), set(new.Required)
+
+	for _, prop := range sortedKeys(old.Properties) {
+		field := name + "." + prop
+
+		newProp, ok := new.Properties[prop]
+		if !ok {
+			if dir == response {
+				d.add(Breaking, "%s removed", field)
+			} else {
+				d.add(NonBreaking, "%s removed", field)
+			}
+
+			continue
+		}
+
+		if dir == request && !oldRequired[prop] && newRequired[prop] {
+			d.add(Breaking, "%s became required", field)
+		}
+
+		if dir == response && oldRequired[prop] && !newRequired[prop] {
+			d.add(Breaking, "%s became optional", field)
+		}
+
+		oldProp := old.Properties[prop]
+
+		d.schema(field, dir, &oldProp, &newProp, seen)
+	}
+
+	for _, prop := range sortedKeys(new.Properties) {
+		if _, ok := old.Properties[prop]; ok {
+			continue
+		}
+
+		field := name + "." + prop
+
+		if dir == request && newRequired[prop] {
+			d.add(Breaking, "required %s added", field)
+		} else {
+			d.add(NonBreaking, "%s added", field)
+		}
+	}
+}
+
+type operation struct {
+	name string
+	op   *openapi3.Operation
+}
+
+// operations returns the operations of a spec keyed by method and path, path params are normalized so renaming them is not a change
+func operations(s *openapi3.Spec) map[string]operation {
+	ops := map[string]operation{}
+
+	for path, item := range s.Paths.MapOfPathItemValues {
+		for method, op := range item.MapOfOperationValues {
+			op := op
+			method = strings.ToUpper(method)
+
+			ops[method+" "+normalizePath(path)] = operation{
+				name: method + " " + path,
+				op:   &op,
+			}
+		}
+	}
+
+	return ops
+}
+
+func normalizePath(path string) string {
+	parts := strings.Split(path, "/")
+
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = "{}"
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+func params(op *openapi3.Operation) map[string]*openapi3.Parameter {
+	res := map[string]*openapi3.Parameter{}
+
+	for _, p := range op.Parameters {
+		if p.Parameter == nil {
+			continue
+		}
+
+		// Path params are matched by position through the normalized path
+		if p.Parameter.In == openapi3.ParameterInPath {
+			continue
+		}
+
+		res[string(p.Parameter.In)+" param "+p.Parameter.Name] = p.Parameter
+	}
+
+	return res
+}
+
+func resolve(s *openapi3.Spec, ref *openapi3.SchemaOrRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+
+	if ref.Schema != nil {
+		return ref.Schema
+	}
+
+	if ref.SchemaReference == nil || s.Components == nil || s.Components.Schemas == nil {
+		return nil
+	}
+
+	name := strings.TrimPrefix(ref.SchemaReference.Ref, "#/components/schemas/")
+
+	if schema, ok := s.Components.Schemas.MapOfSchemaOrRefValues[name]; ok {
+		return resolve(s, &schema)
+	}
+
+	return nil
+}
+
+func schemaType(s *openapi3.Schema) string {
+	if s.Type == nil {
+		if len(s.Properties) > 0 {
+			return string(openapi3.SchemaTypeObject)
+		}
+
+		return "any"
+	}
+
+	return string(*s.Type)
+}
+
+// missing returns the values of a that are not in b
+func missing(a, b []interface{}) []interface{} {
+	res := []interface{}{}
+
+	for _, av := range a {
+		found := false
+
+		for _, bv := range b {
+			if reflect.DeepEqual(av, bv) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			res = append(res, av)
+		}
+	}
+
+	return res
+}
+
+func set(values []string) map[string]bool {
+	res := map[string]bool{}
+
+	for _, v := range values {
+		res[v] = true
+	}
+
+	return res
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,222 @@
+package spec
+
+import (
+	"testing"
+	"time"
+)
+
+type userV1 struct {
+	ID    int    `json:"id" required:"true"`
+	Name  string `json:"name" required:"true"`
+	State string `json:"state" enum:"active,blocked"`
+}
+
+type userV2 struct {
+	ID    int    `json:"id" required:"true"`
+	State string `json:"state" enum:"active,blocked"`
+}
+
+type userV3 struct {
+	ID    int    `json:"id" required:"true"`
+	Name  string `json:"name" required:"true"`
+	Email string `json:"email" required:"true"`
+	State string `json:"state" enum:"active,blocked"`
+}
+
+type userV4 struct {
+	ID    string `json:"id" required:"true"`
+	Name  string `json:"name" required:"true"`
+	State string `json:"state" enum:"active,blocked"`
+}
+
+type userV5 struct {
+	ID    int    `json:"id" required:"true"`
+	Name  string `json:"name"`
+	State string `json:"state" enum:"active,blocked"`
+}
+
+type userV6 struct {
+	ID    int    `json:"id" required:"true"`
+	Name  string `json:"name" required:"true"`
+	State string `json:"state" enum:"active"`
+}
+
+type userV7 struct {
+	ID    int    `json:"id" required:"true"`
+	Name  string `json:"name" required:"true"`
+	State string `json:"state" enum:"active,blocked,deleted"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []*OAS
+		want     []string
+	}{
+		{
+			name: "unchanged",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV1{})},
+		},
+		{
+			name: "operation removed",
+			old:  []*OAS{Of("/users").Get([]userV1{}), Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users").Get([]userV1{})},
+			want: []string{"[breaking] GET /users/{id}: operation removed"},
+		},
+		{
+			name: "operation added",
+			old:  []*OAS{Of("/users").Get([]userV1{})},
+			new:  []*OAS{Of("/users").Get([]userV1{}), Of("/users/:id").Get(userV1{})},
+			want: []string{"[non-breaking] GET /users/{id}: operation added"},
+		},
+		{
+			name: "path param renamed",
+			old:  []*OAS{Of("/users/:id").Get(userV1{}).SetOperationID("getUser")},
+			new:  []*OAS{Of("/users/:userID").Get(userV1{}).SetOperationID("getUser")},
+		},
+		{
+			name: "operationId changed",
+			old:  []*OAS{Of("/users/:id").Get(userV1{}).SetOperationID("getUser")},
+			new:  []*OAS{Of("/users/:id").Get(userV1{}).SetOperationID("findUser")},
+			want: []string{"[breaking] GET /users/{id}: operationId changed from getUser to findUser"},
+		},
+		{
+			name: "deprecated",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV1{}).Deprecate(time.Time{})},
+			want: []string{"[non-breaking] GET /users/{id}: operation deprecated"},
+		},
+		{
+			name: "required param added",
+			old:  []*OAS{Of("/users").Get([]userV1{})},
+			new:  []*OAS{Of("/users").Get([]userV1{}).AddQueryParam("q", ParamRequired())},
+			want: []string{"[breaking] GET /users: required query param q added"},
+		},
+		{
+			name: "optional param added",
+			old:  []*OAS{Of("/users").Get([]userV1{})},
+			new:  []*OAS{Of("/users").Get([]userV1{}).AddQueryParam("q")},
+			want: []string{"[non-breaking] GET /users: optional query param q added"},
+		},
+		{
+			name: "param became required",
+			old:  []*OAS{Of("/users").Get([]userV1{}).AddQueryParam("q")},
+			new:  []*OAS{Of("/users").Get([]userV1{}).AddQueryParam("q", ParamRequired())},
+			want: []string{"[breaking] GET /users: query param q became required"},
+		},
+		{
+			name: "param removed",
+			old:  []*OAS{Of("/users").Get([]userV1{}).AddQueryParam("q", ParamRequired())},
+			new:  []*OAS{Of("/users").Get([]userV1{})},
+			want: []string{"[non-breaking] GET /users: query param q removed"},
+		},
+		{
+			name: "response field removed",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV2{})},
+			want: []string{"[breaking] GET /users/{id}: response 200.name removed"},
+		},
+		{
+			name: "response field added",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV3{})},
+			want: []string{"[non-breaking] GET /users/{id}: response 200.email added"},
+		},
+		{
+			name: "response field became optional",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV5{})},
+			want: []string{"[breaking] GET /users/{id}: response 200.name became optional"},
+		},
+		{
+			name: "response type changed",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV4{})},
+			want: []string{"[breaking] GET /users/{id}: response 200.id type changed from integer to string"},
+		},
+		{
+			name: "response items changed",
+			old:  []*OAS{Of("/users").Get([]userV1{})},
+			new:  []*OAS{Of("/users").Get([]userV2{})},
+			want: []string{"[breaking] GET /users: response 200[].name removed"},
+		},
+		{
+			name: "response enum narrowed",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV6{})},
+			want: []string{"[non-breaking] GET /users/{id}: response 200.state enum narrowed, removed [blocked]"},
+		},
+		{
+			name: "response enum widened",
+			old:  []*OAS{Of("/users/:id").Get(userV1{})},
+			new:  []*OAS{Of("/users/:id").Get(userV7{})},
+			want: []string{"[breaking] GET /users/{id}: response 200.state enum widened, added [deleted]"},
+		},
+		{
+			name: "request field removed",
+			old:  []*OAS{Of("/users").Post(nil, userV1{})},
+			new:  []*OAS{Of("/users").Post(nil, userV2{})},
+			want: []string{"[non-breaking] POST /users: request body.name removed"},
+		},
+		{
+			name: "required request field added",
+			old:  []*OAS{Of("/users").Post(nil, userV1{})},
+			new:  []*OAS{Of("/users").Post(nil, userV3{})},
+			want: []string{"[breaking] POST /users: required request body.email added"},
+		},
+		{
+			name: "request field became required",
+			old:  []*OAS{Of("/users").Post(nil, userV5{})},
+			new:  []*OAS{Of("/users").Post(nil, userV1{})},
+			want: []string{"[breaking] POST /users: request body.name became required"},
+		},
+		{
+			name: "request enum narrowed",
+			old:  []*OAS{Of("/users").Post(nil, userV1{})},
+			new:  []*OAS{Of("/users").Post(nil, userV6{})},
+			want: []string{"[breaking] POST /users: request body.state enum narrowed, removed [blocked]"},
+		},
+		{
+			name: "request enum widened",
+			old:  []*OAS{Of("/users").Post(nil, userV1{})},
+			new:  []*OAS{Of("/users").Post(nil, userV7{})},
+			want: []string{"[non-breaking] POST /users: request body.state enum widened, added [deleted]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Diff(build(t, tt.old...), build(t, tt.new...))
+
+			if len(changes) != len(tt.want) {
+				t.Fatalf("got changes %v, want %v", changes, tt.want)
+			}
+
+			for i, change := range changes {
+				if change.String() != tt.want[i] {
+					t.Errorf("got %s, want %s", change, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChangesBreaking(t *testing.T) {
+	changes := Changes{
+		{Kind: NonBreaking, Operation: "GET /users", Message: "operation added"},
+		{Kind: Breaking, Operation: "GET /users/{id}", Message: "operation removed"},
+	}
+
+	if !changes.HasBreaking() {
+		t.Error("changes have no breaking change")
+	}
+
+	if breaking := changes.Breaking(); len(breaking) != 1 || breaking[0] != changes[1] {
+		t.Errorf("got breaking changes %v, want %v", breaking, changes[1:])
+	}
+
+	if changes[:1].HasBreaking() {
+		t.Error("non-breaking changes have a breaking change")
+	}
+}
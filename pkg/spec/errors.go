@@ -0,0 +1,112 @@
+package spec
+
+import (
+	"net/http"
+	"sort"
+)
+
+// SetErrors replaces the default error responses of the route
+func (o *OAS) SetErrors(codes ...int) *OAS {
+	o.errors = codes
+	o.errorsSet = true
+
+	return o
+}
+
+// InheritErrors replaces the default error responses unless the route sets its own, nil keeps the defaults
+func (o *OAS) InheritErrors(codes []int) *OAS {
+	if !o.errorsSet && codes != nil {
+		o.errors = codes
+	}
+
+	return o
+}
+
+// SetErrorBody sets the body documented for error responses, Error by default
+func (o *OAS) SetErrorBody(body interface{}) *OAS {
+	o.errorBody = body
+
+	return o
+}
+
+// InheritErrorBody sets the error body unless the route sets its own
+func (o *OAS) InheritErrorBody(body interface{}) *OAS {
+	if o.errorBody == nil {
+		o.errorBody = body
+	}
+
+	return o
+}
+
+// DropResponses removes default error responses, including the ones added for security and rate limiting
+func (o *OAS) DropResponses(codes ...int) *OAS {
+	if o.dropped == nil {
+		o.dropped = map[int]bool{}
+	}
+
+	for _, code := range codes {
+		o.dropped[code] = true
+	}
+
+	return o
+}
+
+// RateLimited documents the 429 response of a rate limited route
+func (o *OAS) RateLimited() *OAS {
+	o.rateLimited = true
+
+	return o
+}
+
+func (o *OAS) withError(code int) *OAS {
+	o.errors = append(o.errors, code)
+
+	return o
+}
+
+// errorResponses resolves the error responses documented for the route,
+// responses added explicitly with AddResponse take precedence
+func (o *OAS) errorResponses() []*apiResponse {
+	codes := map[int]bool{}
+
+	for _, code := range o.errors {
+		codes[code] = true
+	}
+
+	if len(o.security) > 0 {
+		codes[http.StatusUnauthorized] = true
+		codes[http.StatusForbidden] = true
+	}
+
+	if o.rateLimited {
+		codes[http.StatusTooManyRequests] = true
+	}
+
+	for _, res := range o.out {
+		delete(codes, res.code)
+	}
+
+	body := o.errorBody
+	if body == nil {
+		body = Error{}
+	}
+
+	var responses []*apiResponse
+
+	for code := range codes {
+		if o.dropped[code] {
+			continue
+		}
+
+		responses = append(responses, &apiResponse{
+			code: code,
+			body: body,
+		})
+	}
+
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].code < responses[j].code
+	})
+
+	return responses
+}
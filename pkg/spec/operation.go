@@ -0,0 +1,88 @@
+package spec
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+)
+
+// Operation describes a single documented operation with its Go types, used by client generators
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	Deprecated  bool
+	Params      []Param
+	Consumes    []string
+	Request     reflect.Type
+	Response    reflect.Type
+	SuccessCode int
+}
+
+// Param describes a path, query, header or cookie parameter of an Operation
+type Param struct {
+	Name     string
+	In       string
+	Required bool
+	Type     string
+}
+
+// Operations returns the operations of the route, one per method
+func (o *OAS) Operations() []Operation {
+	var (
+		ops    []Operation
+		params []Param
+	)
+
+	for _, p := range o.params {
+		params = append(params, Param{
+			Name:     p.name,
+			In:       string(p.in),
+			Required: p.required,
+			Type:     string(p.typ),
+		})
+	}
+
+	success := o.success()
+
+	for _, method := range o.methods {
+		op := Operation{
+			ID:          o.operationIDFor(method),
+			Method:      method,
+			Path:        path.Clean(o.path),
+			Summary:     o.summary,
+			Deprecated:  o.deprecated,
+			Params:      params,
+			Consumes:    o.consumes,
+			SuccessCode: http.StatusOK,
+		}
+
+		if hasBody(method) && o.in != nil {
+			op.Request = reflect.TypeOf(o.in)
+		}
+
+		if success != nil {
+			op.SuccessCode = success.code
+
+			if success.body != nil && !success.noContent && method != http.MethodHead {
+				op.Response = reflect.TypeOf(success.body)
+			}
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops
+}
+
+// success returns the first successful response
+func (o *OAS) success() *apiResponse {
+	for _, res := range o.out {
+		if res.code >= 200 && res.code < 300 {
+			return res
+		}
+	}
+
+	return nil
+}
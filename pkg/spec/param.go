@@ -0,0 +1,108 @@
+package spec
+
+import (
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// parameter is a single path, query, header or cookie parameter
+type parameter struct {
+	name        string
+	in          openapi3.ParameterIn
+	required    bool
+	description string
+	typ         openapi3.SchemaType
+	format      string
+	example     interface{}
+}
+
+// ParamOption configures a query, header or cookie parameter
+type ParamOption func(p *parameter)
+
+// ParamRequired marks the parameter as required
+func ParamRequired() ParamOption {
+	return func(p *parameter) {
+		p.required = true
+	}
+}
+
+// ParamDescription sets the parameter description
+func ParamDescription(description string) ParamOption {
+	return func(p *parameter) {
+		p.description = description
+	}
+}
+
+// ParamType sets the schema type of the parameter, e.g. integer or boolean, string by default
+func ParamType(typ string) ParamOption {
+	return func(p *parameter) {
+		p.typ = openapi3.SchemaType(typ)
+	}
+}
+
+// ParamFormat sets the schema format of the parameter, e.g. int64 or date-time
+func ParamFormat(format string) ParamOption {
+	return func(p *parameter) {
+		p.format = format
+	}
+}
+
+// ParamExample sets an example value for the parameter
+func ParamExample(example interface{}) ParamOption {
+	return func(p *parameter) {
+		p.example = example
+	}
+}
+
+func (o *OAS) addParam(name string, in openapi3.ParameterIn, opts ...ParamOption) *OAS {
+	for _, p := range o.params {
+		if p.name == name && p.in == in {
+			return o
+		}
+	}
+
+	p := &parameter{
+		name:     name,
+		in:       in,
+		required: in == openapi3.ParameterInPath,
+		typ:      openapi3.SchemaTypeString,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	o.params = append(o.params, p)
+
+	return o
+}
+
+func (p *parameter) build() *openapi3.Parameter {
+	schema := openapi3.Schema{}
+
+	schema.WithType(p.typ)
+
+	if p.format != "" {
+		schema.WithFormat(p.format)
+	}
+
+	param := openapi3.Parameter{}
+
+	param.
+		WithName(p.name).
+		WithIn(p.in).
+		WithSchema(openapi3.SchemaOrRef{Schema: &schema})
+
+	if p.required {
+		param.WithRequired(true)
+	}
+
+	if p.description != "" {
+		param.WithDescription(p.description)
+	}
+
+	if p.example != nil {
+		param.WithExample(p.example)
+	}
+
+	return &param
+}
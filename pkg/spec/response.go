@@ -0,0 +1,127 @@
+package spec
+
+import (
+	"strconv"
+
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+type apiResponse struct {
+	code        int
+	body        interface{}
+	description string
+	headers     []responseHeader
+	examples    map[string]interface{}
+	noContent   bool
+}
+
+type responseHeader struct {
+	name        string
+	description string
+}
+
+// ResponseOption configures a response added with AddResponse
+type ResponseOption func(r *apiResponse)
+
+// ResDescription sets the response description, the status text by default
+func ResDescription(description string) ResponseOption {
+	return func(r *apiResponse) {
+		r.description = description
+	}
+}
+
+// ResHeader documents a response header, e.g. Location, ETag or X-RateLimit-Remaining
+func ResHeader(name, description string) ResponseOption {
+	return func(r *apiResponse) {
+		r.headers = append(r.headers, responseHeader{name, description})
+	}
+}
+
+// ResExample adds a named example of the response body
+func ResExample(name string, value interface{}) ResponseOption {
+	return func(r *apiResponse) {
+		if r.examples == nil {
+			r.examples = map[string]interface{}{}
+		}
+
+		r.examples[name] = value
+	}
+}
+
+// ResNoContent documents the response without a body
+func ResNoContent() ResponseOption {
+	return func(r *apiResponse) {
+		r.noContent = true
+	}
+}
+
+// AddRequestExample adds a named example of the request body
+func (o *OAS) AddRequestExample(name string, value interface{}) *OAS {
+	if o.examples == nil {
+		o.examples = map[string]interface{}{}
+	}
+
+	o.examples[name] = value
+
+	return o
+}
+
+// apply adds the description, headers and examples to the already built response
+func (r *apiResponse) apply(op *openapi3.Operation) {
+	code := strconv.Itoa(r.code)
+	res := op.Responses.MapOfResponseOrRefValues[code]
+
+	if res.Response == nil {
+		return
+	}
+
+	if r.description != "" {
+		res.Response.Description = r.description
+	}
+
+	if r.noContent {
+		res.Response.Content = nil
+	}
+
+	for _, h := range r.headers {
+		header := openapi3.Header{Schema: stringSchema("")}
+
+		if h.description != "" {
+			desc := h.description
+			header.Description = &desc
+		}
+
+		res.Response.WithHeadersItem(h.name, openapi3.HeaderOrRef{Header: &header})
+	}
+
+	withExamples(res.Response.Content, r.examples)
+
+	op.Responses.WithMapOfResponseOrRefValuesItem(code, res)
+}
+
+// withoutContent drops the bodies of the responses, HEAD responses only have headers
+func withoutContent(op *openapi3.Operation) {
+	for _, res := range op.Responses.MapOfResponseOrRefValues {
+		if res.Response != nil {
+			res.Response.Content = nil
+		}
+	}
+}
+
+func withExamples(content map[string]openapi3.MediaType, examples map[string]interface{}) {
+	if len(examples) == 0 {
+		return
+	}
+
+	for mime, mt := range content {
+		for name, value := range examples {
+			value := value
+
+			mt.WithExamplesItem(name, openapi3.ExampleOrRef{
+				Example: &openapi3.Example{Value: &value},
+			})
+		}
+
+		content[mime] = mt
+	}
+}
@@ -2,14 +2,22 @@ package spec
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/swaggest/openapi-go/openapi3"
 )
 
+var (
+	suggestedMu sync.Mutex
+	// suggested holds, per spec, the operations that were documented with a suggested operationId
+	suggested = map[*openapi3.Spec]map[string]*OAS{}
+)
+
 // JSONObject represents a map[string]interface{} shorthand
 type JSONObject map[string]interface{}
 
@@ -39,23 +47,29 @@ func (e *Error) Data(data *JSONObject) *Error {
 	return e
 }
 
-type apiResponse struct {
-	code int
-	body interface{}
-}
-
 // OAS is the main structure for OpenAPI generation
 type OAS struct {
 	path        string
-	method      string
+	methods     []string
 	in          interface{}
-	params      []string
-	headers     []string
-	query       []string
+	params      []*parameter
 	out         []*apiResponse
 	tags        []string
 	summary     string
 	description string
+	security    []map[string][]string
+	deprecated  bool
+	sunset      time.Time
+	operationID string
+	suggested   bool
+	consumes    []string
+	produces    []string
+	examples    map[string]interface{}
+	errors      []int
+	errorsSet   bool
+	errorBody   interface{}
+	dropped     map[int]bool
+	rateLimited bool
 }
 
 // Of returns an instance of OAS
@@ -68,18 +82,52 @@ func Of(path string, tags ...string) *OAS {
 	return oas.parseParams()
 }
 
-// AddQueryParam adds query params to spec
-func (o *OAS) AddQueryParam(name string) *OAS {
-	o.query = append(o.query, name)
+// Clone returns a copy of the spec, changing the copy leaves the spec untouched, e.g. when a route is built under
+// several prefixes
+func (o *OAS) Clone() *OAS {
+	c := *o
+
+	c.methods = append([]string{}, o.methods...)
+	c.params = append([]*parameter{}, o.params...)
+	c.out = append([]*apiResponse{}, o.out...)
+	c.tags = append([]string{}, o.tags...)
+	c.security = append([]map[string][]string{}, o.security...)
+	c.consumes = append([]string{}, o.consumes...)
+	c.produces = append([]string{}, o.produces...)
+	c.errors = append([]int(nil), o.errors...)
+
+	if o.examples != nil {
+		c.examples = map[string]interface{}{}
+
+		for k, v := range o.examples {
+			c.examples[k] = v
+		}
+	}
 
-	return o
+	if o.dropped != nil {
+		c.dropped = map[int]bool{}
+
+		for k, v := range o.dropped {
+			c.dropped[k] = v
+		}
+	}
+
+	return &c
 }
 
-// AddHeaderParam adds query params to spec
-func (o *OAS) AddHeaderParam(name string) *OAS {
-	o.headers = append(o.headers, name)
+// AddQueryParam adds query params to spec
+func (o *OAS) AddQueryParam(name string, opts ...ParamOption) *OAS {
+	return o.addParam(name, openapi3.ParameterInQuery, opts...)
+}
 
-	return o
+// AddHeaderParam adds header params to spec
+func (o *OAS) AddHeaderParam(name string, opts ...ParamOption) *OAS {
+	return o.addParam(name, openapi3.ParameterInHeader, opts...)
+}
+
+// AddCookieParam adds cookie params to spec
+func (o *OAS) AddCookieParam(name string, opts ...ParamOption) *OAS {
+	return o.addParam(name, openapi3.ParameterInCookie, opts...)
 }
 
 // AddPrefix adds an url prefix
@@ -100,6 +148,27 @@ func (o *OAS) Get(body interface{}, code ...int) *OAS {
 	return o
 }
 
+// Head handles the HEAD request spec, responses are documented without a body
+func (o *OAS) Head(body interface{}, code ...int) *OAS {
+	o.
+		response(body, getCode(code...)).
+		withNotFound().
+		withInternalError().
+		withMethod(http.MethodHead)
+
+	return o
+}
+
+// Options handles the OPTIONS request spec
+func (o *OAS) Options(body interface{}, code ...int) *OAS {
+	o.
+		response(body, getCode(code...)).
+		withInternalError().
+		withMethod(http.MethodOptions)
+
+	return o
+}
+
 // Delete handles the DELETE request spec
 func (o *OAS) Delete(body interface{}, code ...int) *OAS {
 	o.
@@ -150,6 +219,28 @@ func (o *OAS) Patch(body interface{}, data interface{}, code ...int) *OAS {
 	return o
 }
 
+// Match handles the spec for a route serving several methods
+func (o *OAS) Match(methods []string, body interface{}, data interface{}, code ...int) *OAS {
+	o.
+		request(data).
+		response(body, getCode(code...)).
+		withNotFound()
+
+	for _, method := range methods {
+		if hasBody(method) {
+			o.withBadRequest()
+
+			break
+		}
+	}
+
+	o.
+		withInternalError().
+		withMethod(methods...)
+
+	return o
+}
+
 // AddSummary adds a summary for the route
 func (o *OAS) AddSummary(summary string) *OAS {
 	o.summary = summary
@@ -180,34 +271,99 @@ func (o *OAS) ReplaceTags(tags ...string) *OAS {
 	return o
 }
 
-// AddResponse adds an additional response to spec
-func (o *OAS) AddResponse(body interface{}, code int) *OAS {
-	return o.response(body, code)
+// AddSecurity adds a security requirement for the route
+func (o *OAS) AddSecurity(name string, scopes ...string) *OAS {
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	o.security = append(o.security, map[string][]string{name: scopes})
+
+	return o
+}
+
+// InheritSecurity sets security requirements unless the route declares its own
+func (o *OAS) InheritSecurity(security []map[string][]string) *OAS {
+	if len(o.security) == 0 {
+		o.security = security
+	}
+
+	return o
+}
+
+// Deprecate marks the route as deprecated, a non-zero sunset is published as x-sunset
+func (o *OAS) Deprecate(sunset time.Time) *OAS {
+	o.deprecated = true
+	o.sunset = sunset
+
+	return o
+}
+
+// SetOperationID sets the operationId, routes serving several methods get the method appended.
+// Without one the operationId is derived from the method and path.
+func (o *OAS) SetOperationID(id string) *OAS {
+	o.operationID = id
+	o.suggested = false
+
+	return o
+}
+
+// SuggestOperationID sets an operationId derived e.g. from the handler name, unlike SetOperationID it is not an error
+// when it is already used, the operationId is then derived from the method and path
+func (o *OAS) SuggestOperationID(id string) *OAS {
+	o.operationID = id
+	o.suggested = true
+
+	return o
+}
+
+// AddResponse adds an additional response to spec, replacing the one documented with the same code
+func (o *OAS) AddResponse(body interface{}, code int, opts ...ResponseOption) *OAS {
+	res := &apiResponse{
+		code: code,
+		body: body,
+	}
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	for i, existing := range o.out {
+		if existing.code == code {
+			o.out[i] = res
+
+			return o
+		}
+	}
+
+	o.out = append(o.out, res)
+
+	return o
 }
 
 func (o *OAS) withNotFound() *OAS {
-	return o.response(Error{}, http.StatusNotFound)
+	return o.withError(http.StatusNotFound)
 }
 
 func (o *OAS) withBadRequest() *OAS {
-	return o.response(Error{}, http.StatusBadRequest)
+	return o.withError(http.StatusBadRequest)
 }
 
 func (o *OAS) withInternalError() *OAS {
-	return o.response(Error{}, http.StatusInternalServerError)
+	return o.withError(http.StatusInternalServerError)
 }
 
 func (o *OAS) response(body interface{}, code int) *OAS {
 	o.out = append(o.out, &apiResponse{
-		code,
-		body,
+		code: code,
+		body: body,
 	})
 
 	return o
 }
 
-func (o *OAS) withMethod(method string) *OAS {
-	o.method = method
+func (o *OAS) withMethod(methods ...string) *OAS {
+	o.methods = methods
 
 	return o
 }
@@ -223,7 +379,7 @@ func (o *OAS) parseParams() *OAS {
 		if strings.HasPrefix(segment, ":") {
 			segment = strings.ReplaceAll(segment, ":", "")
 
-			o.params = append(o.params, segment)
+			o.addParam(segment, openapi3.ParameterInPath)
 			o.path = strings.ReplaceAll(
 				o.path,
 				fmt.Sprintf(":%s", segment),
@@ -235,92 +391,196 @@ func (o *OAS) parseParams() *OAS {
 	return o
 }
 
-func (o *OAS) createParam(id, location string) *openapi3.Parameter {
-	var t openapi3.SchemaType = "string"
-
-	param := openapi3.Parameter{}
+// Build constructs the OpenAPI spec for a single request
+func (o *OAS) Build(ref *openapi3.Reflector) error {
+	var (
+		params []openapi3.ParameterOrRef
+	)
 
-	param.
-		WithName(id).
-		WithIn(openapi3.ParameterInPath).
-		WithRequired(true).
-		WithContentItem(id, openapi3.MediaType{
-			Schema: &openapi3.SchemaOrRef{
-				Schema: &openapi3.Schema{
-					Title: &id,
-					Type:  &t,
-				},
-			},
+	for _, p := range o.params {
+		params = append(params, openapi3.ParameterOrRef{
+			Parameter: p.build(),
 		})
+	}
 
-	if location == "header" {
-		param.
-			WithIn(openapi3.ParameterInHeader).
-			WithLocation(openapi3.ParameterLocation{
-				HeaderParameter: &openapi3.HeaderParameter{},
-			})
+	if o.suggested {
+		for _, method := range o.methods {
+			if findOperationID(ref.Spec, o.operationIDFor(method)) != "" {
+				o.operationID = ""
+
+				break
+			}
+		}
 	}
 
-	if location == "query" {
-		param.
-			WithIn(openapi3.ParameterInHeader).
-			WithLocation(openapi3.ParameterLocation{
-				QueryParameter: &openapi3.QueryParameter{},
-			})
+	suggestedMu.Lock()
+	defer suggestedMu.Unlock()
+
+	for _, method := range o.methods {
+		id := o.operationIDFor(method)
+
+		if earlier := suggested[ref.Spec][id]; earlier != nil && !o.suggested {
+			if err := earlier.yield(ref.Spec); err != nil {
+				return err
+			}
+		}
+
+		if existing := findOperationID(ref.Spec, id); existing != "" {
+			return fmt.Errorf("duplicate operationId %s for %s %s, already used by %s", id, method, o.path, existing)
+		}
+
+		op := openapi3.Operation{}
+
+		op.
+			WithID(id).
+			WithParameters(params...).
+			WithSecurity(o.security...).
+			WithTags(o.tags...).
+			WithSummary(o.summary).
+			WithDescription(o.description)
+
+		if o.deprecated {
+			op.WithDeprecated(true)
+
+			if !o.sunset.IsZero() {
+				op.WithMapOfAnythingItem("x-sunset", o.sunset.UTC().Format(time.RFC3339))
+			}
+		}
+
+		responses := append(append([]*apiResponse{}, o.out...), o.errorResponses()...)
+
+		for _, response := range responses {
+			if err := o.setResponse(ref, &op, response); err != nil {
+				return fmt.Errorf("%s %s: response %d: %w", method, o.path, response.code, err)
+			}
+		}
+
+		for _, response := range responses {
+			response.apply(&op)
+		}
+
+		if method == http.MethodHead {
+			withoutContent(&op)
+		}
+
+		if hasBody(method) {
+			if err := o.setRequest(ref, &op, method); err != nil {
+				return fmt.Errorf("%s %s: request: %w", method, o.path, err)
+			}
+
+			if op.RequestBody != nil && op.RequestBody.RequestBody != nil {
+				withExamples(op.RequestBody.RequestBody.Content, o.examples)
+			}
+		}
+
+		if err := ref.Spec.AddOperation(method, path.Clean(o.path), op); err != nil {
+			return fmt.Errorf("%s %s: %w", method, o.path, err)
+		}
+
+		if o.suggested && o.operationID != "" {
+			if suggested[ref.Spec] == nil {
+				suggested[ref.Spec] = map[string]*OAS{}
+			}
+
+			suggested[ref.Spec][id] = o
+		}
 	}
 
-	return &param
+	return nil
 }
 
-// Build constructs the OpenAPI spec for a single request
-func (o *OAS) Build(ref *openapi3.Reflector) *OAS {
-	op := openapi3.Operation{}
+// yield moves the operations documented with a suggested operationId to their method and path fallback,
+// so that an operationId set explicitly in a later Build can take it over
+func (o *OAS) yield(s *openapi3.Spec) error {
+	ids := map[string]string{}
 
-	var (
-		params []openapi3.ParameterOrRef
-	)
+	for _, method := range o.methods {
+		ids[method] = o.operationIDFor(method)
+	}
 
-	for _, p := range o.params {
-		params = append(params, openapi3.ParameterOrRef{
-			Parameter: o.createParam(p, "path"),
-		})
+	o.operationID = ""
+
+	item := s.Paths.MapOfPathItemValues[path.Clean(o.path)]
+
+	for _, method := range o.methods {
+		delete(suggested[s], ids[method])
+
+		id := o.operationIDFor(method)
+
+		if existing := findOperationID(s, id); existing != "" {
+			return fmt.Errorf("duplicate operationId %s for %s %s, already used by %s", id, method, o.path, existing)
+		}
+
+		op := item.MapOfOperationValues[strings.ToLower(method)]
+		op.WithID(id)
+		item.MapOfOperationValues[strings.ToLower(method)] = op
 	}
 
-	for _, q := range o.query {
-		params = append(params, openapi3.ParameterOrRef{
-			Parameter: o.createParam(q, "query"),
-		})
+	s.Paths.MapOfPathItemValues[path.Clean(o.path)] = item
+
+	return nil
+}
+
+func (o *OAS) operationIDFor(method string) string {
+	if o.operationID == "" {
+		return camel(append([]string{strings.ToLower(method)}, pathWords(o.path)...)...)
 	}
 
-	for _, h := range o.headers {
-		params = append(params, openapi3.ParameterOrRef{
-			Parameter: o.createParam(h, "header"),
-		})
+	if len(o.methods) > 1 {
+		return camel(o.operationID, strings.ToLower(method))
 	}
 
-	op.
-		WithParameters(params...).
-		WithTags(o.tags...).
-		WithSummary(o.summary).
-		WithDescription(o.description)
+	return o.operationID
+}
 
-	for _, response := range o.out {
-		handleError(ref.SetJSONResponse(&op, response.body, response.code))
-	}
+// pathWords turns /users/{id}/keys into users, by, id, keys
+func pathWords(p string) []string {
+	var words []string
 
-	if o.method == http.MethodPost || o.method == http.MethodPut || o.method == http.MethodPatch {
-		handleError(ref.SetRequest(&op, o.in, o.method))
+	for _, segment := range strings.Split(p, "/") {
+		if strings.HasPrefix(segment, "{") {
+			words = append(words, "by", strings.Trim(segment, "{}"))
+		} else if segment != "" {
+			words = append(words, segment)
+		}
 	}
 
-	handleError(ref.Spec.AddOperation(o.method, path.Clean(o.path), op))
+	return words
+}
 
-	return o
+// camel joins words into a lowerCamelCase identifier, dropping non-alphanumeric characters
+func camel(words ...string) string {
+	var b strings.Builder
+
+	for _, word := range words {
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if b.Len() == 0 {
+				b.WriteString(strings.ToLower(part[:1]) + part[1:])
+			} else {
+				b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+			}
+		}
+	}
+
+	return b.String()
 }
 
-func handleError(err error) {
-	if err != nil {
-		log.Print(err)
+func findOperationID(s *openapi3.Spec, id string) string {
+	for p, item := range s.Paths.MapOfPathItemValues {
+		for method, op := range item.MapOfOperationValues {
+			if op.ID != nil && *op.ID == id {
+				return strings.ToUpper(method) + " " + p
+			}
+		}
 	}
+
+	return ""
+}
+
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
 }
 
 func getCode(code ...int) int {
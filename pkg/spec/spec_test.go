@@ -0,0 +1,330 @@
+package spec
+
+import (
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+type sample struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newReflector() *openapi3.Reflector {
+	ref := &openapi3.Reflector{}
+	ref.Spec = &openapi3.Spec{Openapi: "3.0.3"}
+	ref.Spec.Info.WithTitle("test").WithVersion("1.0.0")
+
+	return ref
+}
+
+func build(t *testing.T, oas ...*OAS) *openapi3.Spec {
+	t.Helper()
+
+	ref := newReflector()
+
+	for _, o := range oas {
+		if err := o.Build(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Validate(ref.Spec); err != nil {
+		t.Fatal(err)
+	}
+
+	return ref.Spec
+}
+
+func findOperation(t *testing.T, s *openapi3.Spec, method, path string) *openapi3.Operation {
+	t.Helper()
+
+	item, ok := s.Paths.MapOfPathItemValues[path]
+	if !ok {
+		t.Fatalf("no path %s", path)
+	}
+
+	op, ok := item.MapOfOperationValues[method]
+	if !ok {
+		t.Fatalf("no %s %s", method, path)
+	}
+
+	return &op
+}
+
+func TestHeadResponsesHaveNoContent(t *testing.T) {
+	tests := []struct {
+		name string
+		oas  *OAS
+	}{
+		{"head", Of("/files/:id").Head(sample{})},
+		{"match", Of("/files/:id").Match([]string{http.MethodGet, http.MethodHead}, sample{}, nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := build(t, tt.oas)
+
+			for code, res := range findOperation(t, s, "head", "/files/{id}").Responses.MapOfResponseOrRefValues {
+				if len(res.Response.Content) > 0 {
+					t.Errorf("HEAD response %s has content", code)
+				}
+			}
+		})
+	}
+
+	s := build(t, Of("/files/:id").Match([]string{http.MethodGet, http.MethodHead}, sample{}, nil))
+
+	if len(findOperation(t, s, "get", "/files/{id}").Responses.MapOfResponseOrRefValues["200"].Response.Content) == 0 {
+		t.Error("GET response of a matched route lost its content")
+	}
+}
+
+type untagged struct {
+	SourceURL string
+	Size      int
+}
+
+func TestRequestBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		properties []string
+	}{
+		{"tagged", sample{}, []string{"id", "name"}},
+		{"untagged", untagged{}, []string{"SourceURL", "Size"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := build(t, Of("/images").Post(sample{}, tt.body, http.StatusAccepted))
+
+			body := findOperation(t, s, "post", "/images").RequestBody
+			if !hasContent(body, MIMEJSON) {
+				t.Fatal("no JSON request body")
+			}
+
+			media := body.RequestBody.Content[MIMEJSON]
+			schema := resolve(s, media.Schema)
+
+			if schema == nil {
+				t.Fatal("request body has no schema")
+			}
+
+			for _, prop := range tt.properties {
+				if _, ok := schema.Properties[prop]; !ok {
+					t.Errorf("request body has no property %s", prop)
+				}
+			}
+		})
+	}
+}
+
+type meta struct {
+	Tag string `json:"tag" form:"tag" xml:"tag"`
+}
+
+type upload struct {
+	Name  string                  `json:"name" form:"title" xml:"label"`
+	File  *multipart.FileHeader   `json:"-" form:"file" xml:"-"`
+	Files []*multipart.FileHeader `json:"-" form:"files" xml:"-"`
+	Meta  meta                    `json:"meta" form:"meta" xml:"meta"`
+}
+
+func TestRequestContent(t *testing.T) {
+	tests := []struct {
+		name string
+		mime string
+		body interface{}
+		// ref is the component of the body schema, type and format describe an inline schema otherwise
+		ref        string
+		properties []string
+		typ        string
+		format     string
+	}{
+		{name: "json", mime: MIMEJSON, body: upload{}, ref: "SpecUpload", properties: []string{"name", "meta"}},
+		{name: "form", mime: MIMEForm, body: upload{}, ref: "FormSpecUpload", properties: []string{"title", "file", "files", "meta"}},
+		{name: "multipart", mime: MIMEMultipart, body: upload{}, ref: "FormSpecUpload", properties: []string{"title", "file", "files", "meta"}},
+		{name: "xml", mime: MIMEXML, body: upload{}, ref: "XmlSpecUpload", properties: []string{"label", "meta"}},
+		{name: "bytes", mime: MIMEOctetStream, body: []byte{}, typ: "string", format: "binary"},
+		{name: "string", mime: MIMECSV, body: "", typ: "string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := build(t, Of("/uploads").AddConsumes(tt.mime).Post(sample{}, tt.body, http.StatusOK))
+
+			body := findOperation(t, s, "post", "/uploads").RequestBody
+			if body == nil || body.RequestBody == nil {
+				t.Fatal("no request body")
+			}
+
+			media, ok := body.RequestBody.Content[tt.mime]
+			if !ok {
+				t.Fatalf("no %s request body", tt.mime)
+			}
+
+			if tt.ref != "" {
+				if media.Schema.SchemaReference == nil || media.Schema.SchemaReference.Ref != "#/components/schemas/"+tt.ref {
+					t.Fatalf("request body is not a reference to %s", tt.ref)
+				}
+
+				schema := resolve(s, media.Schema)
+
+				if len(schema.Properties) != len(tt.properties) {
+					t.Errorf("request body has %d properties, want %v", len(schema.Properties), tt.properties)
+				}
+
+				for _, prop := range tt.properties {
+					if _, ok := schema.Properties[prop]; !ok {
+						t.Errorf("request body has no property %s", prop)
+					}
+				}
+
+				return
+			}
+
+			schema := resolve(s, media.Schema)
+
+			if schema == nil || schema.Type == nil || string(*schema.Type) != tt.typ || str(schema.Format) != tt.format {
+				t.Errorf("request body schema is not a %s with format %q", tt.typ, tt.format)
+			}
+		})
+	}
+}
+
+func TestFileFields(t *testing.T) {
+	s := build(t, Of("/uploads").AddConsumes(MIMEMultipart).Post(sample{}, upload{}, http.StatusOK))
+
+	schema := resolve(s, findOperation(t, s, "post", "/uploads").RequestBody.RequestBody.Content[MIMEMultipart].Schema)
+
+	binary := func(ref openapi3.SchemaOrRef) bool {
+		file := resolve(s, &ref)
+
+		return file != nil && file.Type != nil && *file.Type == openapi3.SchemaTypeString && str(file.Format) == "binary"
+	}
+
+	if !binary(schema.Properties["file"]) {
+		t.Error("file is not a binary string")
+	}
+
+	ref := schema.Properties["files"]
+	files := resolve(s, &ref)
+
+	if files == nil || files.Type == nil || *files.Type != openapi3.SchemaTypeArray || files.Items == nil || !binary(*files.Items) {
+		t.Error("files is not an array of binary strings")
+	}
+}
+
+func TestResponseOptions(t *testing.T) {
+	s := build(t, Of("/files").
+		Post(sample{}, sample{}, http.StatusCreated).
+		AddResponse(sample{}, http.StatusCreated,
+			ResDescription("created"),
+			ResHeader("ETag", "etag desc"),
+			ResHeader("Location", "location desc"),
+			ResExample("first", sample{ID: 1, Name: "first"}),
+		).
+		AddResponse(nil, http.StatusAccepted, ResNoContent()).
+		AddRequestExample("new", sample{Name: "new"}))
+
+	op := findOperation(t, s, "post", "/files")
+
+	created := op.Responses.MapOfResponseOrRefValues["201"].Response
+	if created == nil {
+		t.Fatal("no 201 response")
+	}
+
+	if created.Description != "created" {
+		t.Errorf("201 description %q, want %q", created.Description, "created")
+	}
+
+	for name, want := range map[string]string{"ETag": "etag desc", "Location": "location desc"} {
+		h, ok := created.Headers[name]
+		if !ok || h.Header == nil {
+			t.Errorf("no %s header", name)
+
+			continue
+		}
+
+		if got := str(h.Header.Description); got != want {
+			t.Errorf("%s header description %q, want %q", name, got, want)
+		}
+	}
+
+	if _, ok := created.Content[MIMEJSON].Examples["first"]; !ok {
+		t.Error("201 response has no example first")
+	}
+
+	accepted := op.Responses.MapOfResponseOrRefValues["202"].Response
+	if accepted == nil {
+		t.Fatal("no 202 response")
+	}
+
+	if len(accepted.Content) > 0 {
+		t.Error("202 response has content")
+	}
+
+	if _, ok := op.RequestBody.RequestBody.Content[MIMEJSON].Examples["new"]; !ok {
+		t.Error("request body has no example new")
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		oas      *OAS
+		param    string
+		in       openapi3.ParameterIn
+		required bool
+		typ      openapi3.SchemaType
+	}{
+		{"path", Of("/files/:id").Get(sample{}), "id", openapi3.ParameterInPath, true, openapi3.SchemaTypeString},
+		{"query", Of("/files").AddQueryParam("limit", ParamType("integer")).Get(sample{}), "limit", openapi3.ParameterInQuery, false, openapi3.SchemaTypeInteger},
+		{"header", Of("/files").AddHeaderParam("X-Request-ID", ParamRequired()).Get(sample{}), "X-Request-ID", openapi3.ParameterInHeader, true, openapi3.SchemaTypeString},
+		{"cookie", Of("/files").AddCookieParam("session").Get(sample{}), "session", openapi3.ParameterInCookie, false, openapi3.SchemaTypeString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := newReflector()
+
+			if err := tt.oas.Build(ref); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Validate(ref.Spec); err != nil {
+				t.Fatalf("document is invalid: %v", err)
+			}
+
+			var found *openapi3.Parameter
+
+			for _, item := range ref.Spec.Paths.MapOfPathItemValues {
+				for _, p := range item.MapOfOperationValues["get"].Parameters {
+					if p.Parameter != nil && p.Parameter.Name == tt.param {
+						found = p.Parameter
+					}
+				}
+			}
+
+			if found == nil {
+				t.Fatalf("no parameter %s", tt.param)
+			}
+
+			if found.In != tt.in {
+				t.Errorf("parameter in %q, want %q", found.In, tt.in)
+			}
+
+			if required := found.Required != nil && *found.Required; required != tt.required {
+				t.Errorf("parameter required %v, want %v", required, tt.required)
+			}
+
+			if found.Schema == nil || found.Schema.Schema == nil || found.Schema.Schema.Type == nil || *found.Schema.Schema.Type != tt.typ {
+				t.Errorf("parameter has no %s schema", tt.typ)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+
+	kin "github.com/getkin/kin-openapi/openapi3"
+	"github.com/swaggest/openapi-go/openapi3"
+)
+
+// Validate checks the document against the OpenAPI 3 specification.
+// It is run on startup and can be used in tests to catch an invalid spec early.
+func Validate(s *openapi3.Spec) error {
+	doc, err := Load(s)
+	if err != nil {
+		return err
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+
+	return nil
+}
+
+// Load converts the document for kin-openapi, which validates documents, requests and responses
+func Load(s *openapi3.Spec) (*kin.T, error) {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := kin.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("loading spec: %w", err)
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/khvh/gwf/pkg/router"
+	"github.com/khvh/gwf/pkg/spec"
+)
+
+// Routes returns a router listing, showing and cancelling runs under /workflows, register it with App.RegisterRoutes
+func Routes(e *Engine) *router.Router {
+	return router.
+		Instance().
+		Group("workflows").
+		Prefix("/workflows").
+		Register(
+			router.Get[[]Run]("", e.list).
+				OperationID("listWorkflowRuns").
+				Summary("List the latest workflow runs").
+				Query("workflow", spec.ParamDescription("Only list runs of the workflow")).
+				Query("limit", spec.ParamType("integer"), spec.ParamDescription("At most 100, 20 by default")).
+				Errors(http.StatusBadRequest, http.StatusInternalServerError),
+			router.Get[Run]("/:id", e.get).
+				OperationID("getWorkflowRun").
+				Summary("Get a workflow run"),
+			router.Post[Run, interface{}]("/:id/cancel", e.cancel).
+				OperationID("cancelWorkflowRun").
+				Summary("Cancel a workflow run").
+				Errors(http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+		)
+}
+
+func (e *Engine) list(c echo.Context) error {
+	limit := 20
+
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, spec.Err("invalid_limit").Message("limit must be a positive integer"))
+		}
+
+		limit = n
+	}
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	runs, err := e.List(c.Request().Context(), c.QueryParam("workflow"), limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+func (e *Engine) get(c echo.Context) error {
+	run, err := e.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return runError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func (e *Engine) cancel(c echo.Context) error {
+	run, err := e.Cancel(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return runError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func runError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return c.JSON(http.StatusNotFound, spec.Err("not_found").Message(err.Error()))
+	case errors.Is(err, ErrFinished):
+		return c.JSON(http.StatusConflict, spec.Err("finished").Message(err.Error()))
+	}
+
+	return err
+}
@@ -0,0 +1,261 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+
+	ctxlog "github.com/khvh/gwf/pkg/logger"
+	"github.com/khvh/gwf/pkg/queue"
+)
+
+// Engine runs workflows as queue tasks and keeps their state in a Store
+type Engine struct {
+	store     Store
+	client    *queue.Client
+	workflows map[string]*Workflow
+}
+
+// message is the payload of the task running a step
+type message struct {
+	Run   string          `json:"run"`
+	Stage int             `json:"stage"`
+	Index int             `json:"index"`
+	Width int             `json:"width"`
+	Input json.RawMessage `json:"input"`
+}
+
+// NewEngine creates an engine for the workflows, Mount it on a queue to run them
+func NewEngine(store Store, workflows ...*Workflow) *Engine {
+	e := &Engine{
+		store:     store,
+		workflows: map[string]*Workflow{},
+	}
+
+	for _, w := range workflows {
+		e.workflows[w.name] = w
+	}
+
+	return e
+}
+
+// Mount registers a handler for every workflow with q and starts runs using its client
+func (e *Engine) Mount(q *queue.Queue) *Engine {
+	e.client = q.Client()
+
+	for _, w := range e.workflows {
+		q.AddHandler(w.taskType(), e.handler(w))
+	}
+
+	return e
+}
+
+// Start starts a run of a workflow, input must be of the type the first step takes
+func (e *Engine) Start(ctx context.Context, workflow string, input any) (*Run, error) {
+	w, ok := e.workflows[workflow]
+	if !ok {
+		return nil, fmt.Errorf("unknown workflow %s", workflow)
+	}
+
+	if e.client == nil {
+		return nil, queue.ErrNoClient
+	}
+
+	if t := reflect.TypeOf(input); len(w.stages) > 0 && t != w.input() {
+		return nil, fmt.Errorf("workflow %s takes %s, got %s", workflow, w.input(), t)
+	}
+
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	run := &Run{
+		ID:        uuid.NewString(),
+		Workflow:  workflow,
+		Status:    Running,
+		Stages:    len(w.stages),
+		Input:     b,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := e.store.Create(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if err := e.advance(ctx, w, run, 0, 0, b); err != nil {
+		_ = e.fail(ctx, run, err)
+
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// Get returns a run
+func (e *Engine) Get(ctx context.Context, id string) (*Run, error) {
+	return e.store.Get(ctx, id)
+}
+
+// List returns the latest runs of a workflow, of every workflow when it is empty
+func (e *Engine) List(ctx context.Context, workflow string, limit int) ([]*Run, error) {
+	return e.store.List(ctx, workflow, limit)
+}
+
+// Cancel stops a run, tasks of the run that are queued already do nothing, running ones finish
+func (e *Engine) Cancel(ctx context.Context, id string) (*Run, error) {
+	return e.store.Cancel(ctx, id)
+}
+
+// advance moves the run from stage from to stage and starts it with input, stages fanning out over an empty slice are skipped
+func (e *Engine) advance(ctx context.Context, w *Workflow, run *Run, from, stage int, input []byte) error {
+	for {
+		run.Stage = stage
+		run.UpdatedAt = time.Now().UTC()
+
+		if stage == len(w.stages) {
+			run.Status = Completed
+			run.Width = 0
+			run.Output = input
+
+			return e.store.Advance(ctx, run, from)
+		}
+
+		s := w.stages[stage]
+
+		inputs, err := s.split(input)
+		if err != nil {
+			return e.fail(ctx, run, err)
+		}
+
+		run.Width = len(inputs)
+
+		if err := e.store.Advance(ctx, run, from); err != nil {
+			return err
+		}
+
+		if len(inputs) == 0 {
+			input = []byte("[]")
+			from = stage
+			stage++
+
+			continue
+		}
+
+		// Only this task advanced the run and a retry of it would not start the stage again, the run fails instead
+		for i, in := range inputs {
+			if err := e.enqueue(ctx, w, s, message{Run: run.ID, Stage: stage, Index: i, Width: len(inputs), Input: in}); err != nil {
+				_ = e.fail(ctx, run, fmt.Errorf("starting %s: %w", s.step.stepName(), err))
+
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func (e *Engine) enqueue(ctx context.Context, w *Workflow, s stage, m message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	opts := append(append([]asynq.Option{}, w.opts...), s.step.options()...)
+
+	// Task IDs keep the tasks of a stage from being enqueued twice
+	opts = append(opts, asynq.TaskID(fmt.Sprintf("%s:%d:%d", m.Run, m.Stage, m.Index)))
+
+	_, err = e.client.Enqueue(ctx, queue.NewTaskContext(ctx, w.taskType(), b), opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+
+	return err
+}
+
+func (e *Engine) fail(ctx context.Context, run *Run, err error) error {
+	run.Status = Failed
+	run.Error = err.Error()
+	run.UpdatedAt = time.Now().UTC()
+
+	if err := e.store.Update(ctx, run); err != nil && !errors.Is(err, ErrFinished) {
+		return err
+	}
+
+	return nil
+}
+
+func (e *Engine) handler(w *Workflow) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var m message
+
+		if err := json.Unmarshal(queue.Payload(t), &m); err != nil {
+			return fmt.Errorf("invalid %s task: %v: %w", w.taskType(), err, asynq.SkipRetry)
+		}
+
+		if m.Stage >= len(w.stages) {
+			return fmt.Errorf("%s has no stage %d: %w", w.taskType(), m.Stage, asynq.SkipRetry)
+		}
+
+		run, err := e.store.Get(ctx, m.Run)
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("workflow run %s not found: %w", m.Run, asynq.SkipRetry)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if run.Status != Running {
+			ctxlog.Ctx(ctx).Trace().Msgf("Skipping step of %s workflow run [%s]", run.Status, run.ID)
+
+			return nil
+		}
+
+		s := w.stages[m.Stage]
+
+		out, err := s.step.run(ctx, m.Input)
+		if err != nil {
+			// The run fails once the task is not retried anymore, after its last attempt or when the policy of the
+			// queue classifies err as not retryable
+			if !queue.WillRetry(ctx, err) {
+				if err := e.fail(ctx, run, fmt.Errorf("%s: %w", s.step.stepName(), err)); err != nil {
+					return err
+				}
+			}
+
+			return err
+		}
+
+		n, err := e.store.SaveResult(ctx, run.ID, m.Stage, m.Index, out)
+		if err != nil {
+			return err
+		}
+
+		if n < m.Width {
+			return nil
+		}
+
+		outputs, err := e.store.Results(ctx, run.ID, m.Stage)
+		if err != nil {
+			return err
+		}
+
+		err = e.advance(ctx, w, run, m.Stage, m.Stage+1, s.join(outputs))
+		if errors.Is(err, ErrFinished) || errors.Is(err, ErrAdvanced) {
+			return nil
+		}
+
+		return err
+	})
+}
@@ -0,0 +1,322 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/khvh/gwf/pkg/queue"
+)
+
+var errPermanent = errors.New("permanent")
+
+var (
+	split = NewStep("split", func(_ context.Context, in string) ([]string, error) {
+		return strings.Fields(in), nil
+	})
+	upper = NewStep("upper", func(_ context.Context, in string) (string, error) {
+		return strings.ToUpper(in), nil
+	})
+	join = NewStep("join", func(_ context.Context, in []string) (string, error) {
+		return strings.Join(in, " "), nil
+	})
+)
+
+// failing returns a step failing with err until it ran fails times
+func failing(err error, fails int) *Step[string, string] {
+	var runs int32
+
+	return NewStep("failing", func(_ context.Context, in string) (string, error) {
+		if int(atomic.AddInt32(&runs, 1)) <= fails {
+			return "", err
+		}
+
+		return in, nil
+	}, asynq.MaxRetry(3))
+}
+
+func newEngine(t *testing.T, store Store, w *Workflow, opts ...queue.PolicyOption) (*Engine, *queue.Memory) {
+	t.Helper()
+
+	m := queue.NewMemory(queue.Synchronous(), queue.WithRetryDelay(queue.Fixed(time.Minute)))
+	q := queue.New(m).Policy(w.taskType(), opts...)
+	e := NewEngine(store, w).Mount(q)
+
+	q.Run()
+	t.Cleanup(q.Shutdown)
+
+	return e, m
+}
+
+// drain processes the tasks of the queue including the retries
+func drain(t *testing.T, m *queue.Memory) {
+	t.Helper()
+
+	for i := 0; i < 5; i++ {
+		if err := m.Drain(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		m.Advance(time.Minute)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow *Workflow
+		policy   []queue.PolicyOption
+		input    any
+		status   Status
+		output   string
+		error    string
+	}{
+		{
+			name:     "chain",
+			workflow: New("chain").Then(upper).Then(split),
+			input:    "a b",
+			status:   Completed,
+			output:   `["A","B"]`,
+		},
+		{
+			name:     "fan out",
+			workflow: New("fanout").Then(split).FanOut(upper).Then(join),
+			input:    "a b c",
+			status:   Completed,
+			output:   `"A B C"`,
+		},
+		{
+			name:     "fan out first",
+			workflow: New("first").FanOut(upper),
+			input:    []string{"a", "b"},
+			status:   Completed,
+			output:   `["A","B"]`,
+		},
+		{
+			name:     "fan out over nothing",
+			workflow: New("empty").Then(split).FanOut(upper).Then(join),
+			input:    "",
+			status:   Completed,
+			output:   `""`,
+		},
+		{
+			name:     "retried",
+			workflow: New("retried").Then(failing(errPermanent, 2)),
+			input:    "a",
+			status:   Completed,
+			output:   `"a"`,
+		},
+		{
+			name:     "retries exhausted",
+			workflow: New("exhausted").Then(failing(errPermanent, 4)),
+			input:    "a",
+			status:   Failed,
+			error:    "failing: permanent",
+		},
+		{
+			name:     "not retried",
+			workflow: New("skipped").Then(failing(fmt.Errorf("invalid: %w", asynq.SkipRetry), 1)),
+			input:    "a",
+			status:   Failed,
+			error:    "failing: invalid: skip retry for the task",
+		},
+		{
+			name:     "classified as not retryable",
+			workflow: New("classified").Then(failing(errPermanent, 1)),
+			policy: []queue.PolicyOption{queue.WithClassifier(func(err error) (string, bool) {
+				return "permanent", !errors.Is(err, errPermanent)
+			})},
+			input:  "a",
+			status: Failed,
+			error:  "failing: permanent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, m := newEngine(t, NewMemory(), tt.workflow, tt.policy...)
+			ctx := context.Background()
+
+			run, err := e.Start(ctx, tt.workflow.Name(), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			drain(t, m)
+
+			got, err := e.Get(ctx, run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Status != tt.status || string(got.Output) != tt.output || got.Error != tt.error {
+				t.Errorf("run is %s with output %s and error %q, want %s with output %s and error %q",
+					got.Status, got.Output, got.Error, tt.status, tt.output, tt.error)
+			}
+
+			if tt.status == Completed && got.Stage != got.Stages {
+				t.Errorf("completed at stage %d of %d", got.Stage, got.Stages)
+			}
+		})
+	}
+}
+
+func TestEngineStartInvalidInput(t *testing.T) {
+	w := New("invalid").Then(upper)
+	e, _ := newEngine(t, NewMemory(), w)
+
+	if _, err := e.Start(context.Background(), w.Name(), 42); err == nil {
+		t.Error("started a run with an input of another type")
+	}
+
+	if _, err := e.Start(context.Background(), "unknown", "a"); err == nil {
+		t.Error("started a run of an unknown workflow")
+	}
+}
+
+func TestEngineCancel(t *testing.T) {
+	var runs int32
+
+	step := NewStep("count", func(_ context.Context, in string) (string, error) {
+		atomic.AddInt32(&runs, 1)
+
+		return in, nil
+	})
+
+	w := New("cancel").Then(step).Then(step)
+	e, m := newEngine(t, NewMemory(), w)
+	ctx := context.Background()
+
+	run, err := e.Start(ctx, w.Name(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Cancel(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	drain(t, m)
+
+	if runs != 0 {
+		t.Errorf("steps ran %d times after cancelling", runs)
+	}
+
+	got, err := e.Get(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != Cancelled {
+		t.Errorf("run is %s, want %s", got.Status, Cancelled)
+	}
+
+	if _, err := e.Cancel(ctx, run.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("cancelling again: got %v, want %v", err, ErrFinished)
+	}
+}
+
+// racing is a store whose fan-out tasks all save their output before any of them counts the outputs,
+// like the last tasks of a stage finishing together
+type racing struct {
+	*Memory
+	saved    *sync.WaitGroup
+	advanced int32
+}
+
+func (r *racing) SaveResult(ctx context.Context, id string, stage, index int, output []byte) (int, error) {
+	if _, err := r.Memory.SaveResult(ctx, id, stage, index, output); err != nil {
+		return 0, err
+	}
+
+	if r.saved != nil {
+		r.saved.Done()
+		r.saved.Wait()
+	}
+
+	res, err := r.Memory.Results(ctx, id, stage)
+
+	return len(res), err
+}
+
+func (r *racing) Advance(ctx context.Context, run *Run, from int) error {
+	err := r.Memory.Advance(ctx, run, from)
+	if err == nil && from == 0 && run.Stage == 1 {
+		atomic.AddInt32(&r.advanced, 1)
+	}
+
+	return err
+}
+
+func TestEngineFanInAdvancesOnce(t *testing.T) {
+	store := &racing{Memory: NewMemory()}
+	w := New("fanin").FanOut(upper).Then(join)
+	e, m := newEngine(t, store, w)
+	ctx := context.Background()
+
+	run, err := e.Start(ctx, w.Name(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := m.Tasks()
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+
+	store.saved = &sync.WaitGroup{}
+	store.saved.Add(len(tasks))
+
+	var wg sync.WaitGroup
+
+	for _, info := range tasks {
+		wg.Add(1)
+
+		go func(info *queue.TaskInfo) {
+			defer wg.Done()
+
+			if err := e.handler(w).ProcessTask(ctx, asynq.NewTask(info.Type, info.Payload)); err != nil {
+				t.Error(err)
+			}
+		}(info)
+	}
+
+	wg.Wait()
+
+	if store.advanced != 1 {
+		t.Errorf("advanced %d times, want once", store.advanced)
+	}
+
+	for _, info := range m.Tasks() {
+		var msg message
+
+		if err := json.Unmarshal(info.Payload, &msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Stage == 1 && string(msg.Input) != `["A","B"]` {
+			t.Errorf("the next stage takes %s, want [\"A\",\"B\"]", msg.Input)
+		}
+	}
+
+	// The tasks of the first stage are still queued, processing them again does not advance the run
+	store.saved = nil
+	drain(t, m)
+
+	got, err := e.Get(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != Completed || string(got.Output) != `"A B"` {
+		t.Errorf("run is %s with output %s, want %s with output \"A B\"", got.Status, got.Output, Completed)
+	}
+}
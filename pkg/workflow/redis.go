@@ -0,0 +1,335 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// updateScript saves the run ARGV[1] unless it is missing (-1), finished (0) or not at the stage ARGV[3] when it is
+// given (-2). The run and its results in the other keys expire after ARGV[2] milliseconds unless it is 0.
+var updateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+local run = cjson.decode(current)
+if run.status ~= 'running' then
+	return 0
+end
+if ARGV[3] and run.stage ~= tonumber(ARGV[3]) then
+	return -2
+end
+redis.call('SET', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('PEXPIRE', key, ARGV[2])
+	end
+end
+return 1
+`)
+
+// cancelScript sets the status of the run in KEYS[1] to cancelled and its updatedAt to ARGV[1] and returns it, -1 when
+// it is missing and 0 when it is finished. Only these fields change, a concurrent Advance is kept. The run and its
+// results in the other keys expire after ARGV[2] milliseconds unless it is 0.
+var cancelScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+if cjson.decode(current).status ~= 'running' then
+	return 0
+end
+-- The run is edited as a string, decoding and encoding it would change its input and output.
+-- status precedes input and output, so its first occurrence is the field of the run,
+-- and updatedAt is the last field.
+local i, j = string.find(current, '"status":"running"', 1, true)
+local run = string.sub(current, 1, i - 1) .. '"status":"cancelled"' .. string.sub(current, j + 1)
+local k = string.find(run, '"updatedAt":"[^"]*"}$')
+run = string.sub(run, 1, k - 1) .. '"updatedAt":"' .. ARGV[1] .. '"}'
+redis.call('SET', KEYS[1], run)
+if tonumber(ARGV[2]) > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('PEXPIRE', key, ARGV[2])
+	end
+end
+return run
+`)
+
+// RedisOption configures a Redis store
+type RedisOption func(r *Redis)
+
+// WithRetention sets how long finished runs and their results are kept, a week by default, 0 keeps them
+func WithRetention(d time.Duration) RedisOption {
+	return func(r *Redis) {
+		r.retention = d
+	}
+}
+
+// WithListSize sets how many of the latest runs the lists of List keep, 10000 by default, 0 keeps every run
+func WithListSize(n int) RedisOption {
+	return func(r *Redis) {
+		r.listSize = n
+	}
+}
+
+// Redis is a Store keeping runs in Redis under the gwf:workflow: prefix
+type Redis struct {
+	client    redis.UniversalClient
+	retention time.Duration
+	listSize  int
+}
+
+// NewRedis creates a store using client, e.g. the one of the queue: opt.MakeRedisClient().(redis.UniversalClient)
+func NewRedis(client redis.UniversalClient, opts ...RedisOption) *Redis {
+	r := &Redis{
+		client:    client,
+		retention: 7 * 24 * time.Hour,
+		listSize:  10000,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// runKey and resultsKey share the hash tag of the run, so the update script works with Redis Cluster
+func runKey(id string) string {
+	return "gwf:workflow:{" + id + "}:run"
+}
+
+func listKey(workflow string) string {
+	if workflow == "" {
+		return "gwf:workflow:runs"
+	}
+
+	return "gwf:workflow:runs:" + workflow
+}
+
+func resultsKey(id string, stage int) string {
+	return fmt.Sprintf("gwf:workflow:{%s}:results:%d", id, stage)
+}
+
+// Create saves a new run
+func (r *Redis) Create(ctx context.Context, run *Run) error {
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.Set(ctx, runKey(run.ID), b, 0).Err(); err != nil {
+		return err
+	}
+
+	score := float64(run.CreatedAt.UnixNano())
+
+	// The lists are in other slots than the run, they keep the latest runs and List skips the runs that expired
+	_, err = r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range []string{listKey(""), listKey(run.Workflow)} {
+			p.ZAdd(ctx, key, &redis.Z{Score: score, Member: run.ID})
+
+			if r.listSize > 0 {
+				p.ZRemRangeByRank(ctx, key, 0, -int64(r.listSize)-1)
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// Get returns a run
+func (r *Redis) Get(ctx context.Context, id string) (*Run, error) {
+	b, err := r.client.Get(ctx, runKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var run Run
+
+	if err := json.Unmarshal(b, &run); err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// List returns the latest runs
+func (r *Redis) List(ctx context.Context, workflow string, limit int) ([]*Run, error) {
+	ids, err := r.client.ZRevRange(ctx, listKey(workflow), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Run, 0, len(ids))
+
+	for _, id := range ids {
+		run, err := r.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, run)
+	}
+
+	return res, nil
+}
+
+// Update saves a run
+func (r *Redis) Update(ctx context.Context, run *Run) error {
+	return r.update(ctx, run)
+}
+
+// Advance saves a run that is at stage from
+func (r *Redis) Advance(ctx context.Context, run *Run, from int) error {
+	return r.update(ctx, run, from)
+}
+
+// Cancel cancels a running run, only its status and update time change
+func (r *Redis) Cancel(ctx context.Context, id string) (*Run, error) {
+	// The number of stages of a run does not change, it gives the keys of the results
+	run, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{runKey(id)}
+
+	for stage := 0; stage < run.Stages; stage++ {
+		keys = append(keys, resultsKey(id, stage))
+	}
+
+	updated, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// json.Marshal quotes the time, the script adds the quotes itself
+	res, err := cancelScript.Run(ctx, r.client, keys, string(updated[1:len(updated)-1]), r.retention.Milliseconds()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	switch res {
+	case int64(-1):
+		return nil, ErrNotFound
+	case int64(0):
+		return nil, ErrFinished
+	}
+
+	b, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("cancelling run %s: unexpected reply %v", id, res)
+	}
+
+	var cancelled Run
+
+	if err := json.Unmarshal([]byte(b), &cancelled); err != nil {
+		return nil, err
+	}
+
+	return &cancelled, nil
+}
+
+func (r *Redis) update(ctx context.Context, run *Run, from ...int) error {
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{runKey(run.ID)}
+	args := []interface{}{b, 0}
+
+	if run.Status != Running {
+		for stage := 0; stage < run.Stages; stage++ {
+			keys = append(keys, resultsKey(run.ID, stage))
+		}
+
+		args[1] = r.retention.Milliseconds()
+	}
+
+	for _, stage := range from {
+		args = append(args, stage)
+	}
+
+	n, err := updateScript.Run(ctx, r.client, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+
+	switch n {
+	case -1:
+		return ErrNotFound
+	case 0:
+		return ErrFinished
+	case -2:
+		return ErrAdvanced
+	}
+
+	return nil
+}
+
+// SaveResult saves the output of a task
+func (r *Redis) SaveResult(ctx context.Context, id string, stage, index int, output []byte) (int, error) {
+	key := resultsKey(id, stage)
+
+	var n *redis.IntCmd
+
+	_, err := r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSetNX(ctx, key, strconv.Itoa(index), output)
+		n = p.HLen(ctx, key)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n.Val()), nil
+}
+
+// Results returns the outputs of a stage
+func (r *Redis) Results(ctx context.Context, id string, stage int) ([][]byte, error) {
+	values, err := r.client.HGetAll(ctx, resultsKey(id, stage)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(values))
+
+	for k := range values {
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	res := make([][]byte, 0, len(indexes))
+
+	for _, i := range indexes {
+		res = append(res, []byte(values[strconv.Itoa(i)]))
+	}
+
+	return res, nil
+}
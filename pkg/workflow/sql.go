@@ -0,0 +1,313 @@
+package workflow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLOption configures a SQL store
+type SQLOption func(s *SQL)
+
+// WithSQLRetention sets how long finished runs and their results are kept, a week by default, 0 keeps them
+func WithSQLRetention(d time.Duration) SQLOption {
+	return func(s *SQL) {
+		s.retention = d
+	}
+}
+
+// SQL is a Store keeping runs in the gwf_workflow_runs and gwf_workflow_results tables, it supports PostgreSQL and SQLite
+type SQL struct {
+	db        *sqlx.DB
+	retention time.Duration
+}
+
+type sqlRun struct {
+	ID        string    `db:"id"`
+	Workflow  string    `db:"workflow"`
+	Status    string    `db:"status"`
+	Stage     int       `db:"stage"`
+	Stages    int       `db:"stages"`
+	Width     int       `db:"width"`
+	Input     []byte    `db:"input"`
+	Output    []byte    `db:"output"`
+	Error     string    `db:"error"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// NewSQL creates a store in db, call CreateTables unless the tables are created by migrations
+func NewSQL(db *sqlx.DB, opts ...SQLOption) *SQL {
+	s := &SQL{
+		db:        db,
+		retention: 7 * 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// CreateTables creates the tables unless they exist
+func (s *SQL) CreateTables(ctx context.Context) error {
+	blob, timestamp := "BLOB", "TIMESTAMP"
+
+	switch s.db.DriverName() {
+	case "postgres", "pgx":
+		blob, timestamp = "BYTEA", "TIMESTAMPTZ"
+	}
+
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS gwf_workflow_runs (
+	id         VARCHAR(255) PRIMARY KEY,
+	workflow   VARCHAR(255) NOT NULL,
+	status     VARCHAR(32) NOT NULL,
+	stage      INTEGER NOT NULL,
+	stages     INTEGER NOT NULL,
+	width      INTEGER NOT NULL,
+	input      %[1]s,
+	output     %[1]s,
+	error      TEXT NOT NULL,
+	created_at %[2]s NOT NULL,
+	updated_at %[2]s NOT NULL
+)`, blob, timestamp))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS gwf_workflow_runs_updated_at ON gwf_workflow_runs (updated_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS gwf_workflow_results (
+	run_id VARCHAR(255) NOT NULL,
+	stage  INTEGER NOT NULL,
+	idx    INTEGER NOT NULL,
+	output %s,
+	PRIMARY KEY (run_id, stage, idx)
+)`, blob))
+
+	return err
+}
+
+// Create saves a new run, runs that finished before the retention are deleted first
+func (s *SQL) Create(ctx context.Context, run *Run) error {
+	if err := s.expire(ctx, time.Now().UTC().Add(-s.retention)); err != nil {
+		return err
+	}
+
+	_, err := s.db.NamedExecContext(ctx, `INSERT INTO gwf_workflow_runs
+	(id, workflow, status, stage, stages, width, input, output, error, created_at, updated_at) VALUES
+	(:id, :workflow, :status, :stage, :stages, :width, :input, :output, :error, :created_at, :updated_at)`, toSQL(run))
+
+	return err
+}
+
+// Get returns a run
+func (s *SQL) Get(ctx context.Context, id string) (*Run, error) {
+	var r sqlRun
+
+	err := s.db.GetContext(ctx, &r, s.db.Rebind(`SELECT * FROM gwf_workflow_runs WHERE id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.run(), nil
+}
+
+// List returns the latest runs
+func (s *SQL) List(ctx context.Context, workflow string, limit int) ([]*Run, error) {
+	var (
+		rows  []sqlRun
+		where string
+		args  []interface{}
+	)
+
+	if workflow != "" {
+		where = `WHERE workflow = ?`
+		args = append(args, workflow)
+	}
+
+	err := s.db.SelectContext(ctx, &rows, s.db.Rebind(
+		`SELECT * FROM gwf_workflow_runs `+where+` ORDER BY created_at DESC, id LIMIT ?`,
+	), append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Run, 0, len(rows))
+
+	for _, r := range rows {
+		res = append(res, r.run())
+	}
+
+	return res, nil
+}
+
+// Update saves a run
+func (s *SQL) Update(ctx context.Context, run *Run) error {
+	res, err := s.db.NamedExecContext(ctx, `UPDATE gwf_workflow_runs SET
+	status = :status, stage = :stage, width = :width, output = :output, error = :error, updated_at = :updated_at
+	WHERE id = :id AND status = 'running'`, toSQL(run))
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	if _, err := s.Get(ctx, run.ID); err != nil {
+		return err
+	}
+
+	return ErrFinished
+}
+
+// Advance saves a run that is at stage from
+func (s *SQL) Advance(ctx context.Context, run *Run, from int) error {
+	r := toSQL(run)
+
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE gwf_workflow_runs SET
+	status = ?, stage = ?, width = ?, output = ?, error = ?, updated_at = ?
+	WHERE id = ? AND status = 'running' AND stage = ?`), r.Status, r.Stage, r.Width, r.Output, r.Error, r.UpdatedAt, r.ID, from)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	current, err := s.Get(ctx, run.ID)
+	if err != nil {
+		return err
+	}
+
+	if current.Status != Running {
+		return ErrFinished
+	}
+
+	return ErrAdvanced
+}
+
+// Cancel cancels a running run
+func (s *SQL) Cancel(ctx context.Context, id string) (*Run, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE gwf_workflow_runs SET status = ?, updated_at = ?
+	WHERE id = ? AND status = 'running'`), string(Cancelled), time.Now().UTC(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, ErrFinished
+	}
+
+	return run, nil
+}
+
+// SaveResult saves the output of a task
+func (s *SQL) SaveResult(ctx context.Context, id string, stage, index int, output []byte) (int, error) {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`INSERT INTO gwf_workflow_results (run_id, stage, idx, output) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+	), id, stage, index, output)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+
+	err = s.db.GetContext(ctx, &n, s.db.Rebind(`SELECT COUNT(*) FROM gwf_workflow_results WHERE run_id = ? AND stage = ?`), id, stage)
+
+	return n, err
+}
+
+// Results returns the outputs of a stage
+func (s *SQL) Results(ctx context.Context, id string, stage int) ([][]byte, error) {
+	var res [][]byte
+
+	err := s.db.SelectContext(ctx, &res, s.db.Rebind(
+		`SELECT output FROM gwf_workflow_results WHERE run_id = ? AND stage = ? ORDER BY idx`,
+	), id, stage)
+
+	return res, err
+}
+
+// expire deletes the runs that finished before, and their results
+func (s *SQL) expire(ctx context.Context, before time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM gwf_workflow_results WHERE run_id IN
+	(SELECT id FROM gwf_workflow_runs WHERE status <> 'running' AND updated_at < ?)`), before)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM gwf_workflow_runs WHERE status <> 'running' AND updated_at < ?`), before)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func toSQL(run *Run) *sqlRun {
+	return &sqlRun{
+		ID:        run.ID,
+		Workflow:  run.Workflow,
+		Status:    string(run.Status),
+		Stage:     run.Stage,
+		Stages:    run.Stages,
+		Width:     run.Width,
+		Input:     run.Input,
+		Output:    run.Output,
+		Error:     run.Error,
+		CreatedAt: run.CreatedAt,
+		UpdatedAt: run.UpdatedAt,
+	}
+}
+
+func (r *sqlRun) run() *Run {
+	return &Run{
+		ID:        r.ID,
+		Workflow:  r.Workflow,
+		Status:    Status(r.Status),
+		Stage:     r.Stage,
+		Stages:    r.Stages,
+		Width:     r.Width,
+		Input:     r.Input,
+		Output:    r.Output,
+		Error:     r.Error,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
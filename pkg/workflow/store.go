@@ -0,0 +1,240 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for unknown runs
+	ErrNotFound = errors.New("workflow run not found")
+	// ErrFinished is returned when updating a run that completed, failed or was cancelled
+	ErrFinished = errors.New("workflow run is finished")
+	// ErrAdvanced is returned when advancing a run that another task moved to the next stage already
+	ErrAdvanced = errors.New("workflow run advanced already")
+)
+
+// Status is the state of a run
+type Status string
+
+// Statuses of a run, every status but Running is final
+const (
+	Running   Status = "running"
+	Completed Status = "completed"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+)
+
+// Run is the state of a workflow run
+type Run struct {
+	ID        string          `json:"id"`
+	Workflow  string          `json:"workflow"`
+	Status    Status          `json:"status" enum:"running,completed,failed,cancelled"`
+	Stage     int             `json:"stage" description:"Index of the running stage, the number of stages once completed"`
+	Stages    int             `json:"stages"`
+	Width     int             `json:"width" description:"Number of tasks of the running stage"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty" description:"Output of the last stage once completed"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// Store keeps the state of runs and the outputs of their tasks
+type Store interface {
+	// Create saves a new run
+	Create(ctx context.Context, run *Run) error
+	// Get returns a run or ErrNotFound
+	Get(ctx context.Context, id string) (*Run, error)
+	// List returns the latest runs of a workflow, of every workflow when it is empty
+	List(ctx context.Context, workflow string, limit int) ([]*Run, error)
+	// Update saves a run unless it is finished, then it returns ErrFinished
+	Update(ctx context.Context, run *Run) error
+	// Advance saves a run unless it is finished or no longer at stage from, then it returns ErrAdvanced.
+	// The last tasks of a stage may finish together, only the one that advanced the run starts the next stage.
+	Advance(ctx context.Context, run *Run, from int) error
+	// Cancel sets the status of a running run to Cancelled and returns it, ErrFinished when it is finished.
+	// Only the status changes, a stage advancing at the same time is kept.
+	Cancel(ctx context.Context, id string) (*Run, error)
+	// SaveResult saves the output of a task of a stage once and returns the number of outputs the stage has
+	SaveResult(ctx context.Context, id string, stage, index int, output []byte) (int, error)
+	// Results returns the outputs of a stage ordered by task index
+	Results(ctx context.Context, id string, stage int) ([][]byte, error)
+}
+
+// Memory is a Store for tests and local development
+type Memory struct {
+	mu      sync.Mutex
+	runs    map[string]*Run
+	order   []string
+	results map[string]map[int]map[int][]byte
+}
+
+// NewMemory creates an in-process store
+func NewMemory() *Memory {
+	return &Memory{
+		runs:    map[string]*Run{},
+		results: map[string]map[int]map[int][]byte{},
+	}
+}
+
+// Create saves a new run
+func (m *Memory) Create(_ context.Context, run *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := *run
+	m.runs[run.ID] = &r
+	m.order = append(m.order, run.ID)
+	m.results[run.ID] = map[int]map[int][]byte{}
+
+	return nil
+}
+
+// Get returns a run
+func (m *Memory) Get(_ context.Context, id string) (*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run, ok := m.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r := *run
+
+	return &r, nil
+}
+
+// List returns the latest runs
+func (m *Memory) List(_ context.Context, workflow string, limit int) ([]*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := []*Run{}
+
+	for i := len(m.order) - 1; i >= 0 && len(res) < limit; i-- {
+		run := m.runs[m.order[i]]
+
+		if workflow == "" || run.Workflow == workflow {
+			r := *run
+			res = append(res, &r)
+		}
+	}
+
+	return res, nil
+}
+
+// Update saves a run
+func (m *Memory) Update(_ context.Context, run *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.runs[run.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if current.Status != Running {
+		return ErrFinished
+	}
+
+	r := *run
+	m.runs[run.ID] = &r
+
+	return nil
+}
+
+// Advance saves a run that is at stage from
+func (m *Memory) Advance(_ context.Context, run *Run, from int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.runs[run.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if current.Status != Running {
+		return ErrFinished
+	}
+
+	if current.Stage != from {
+		return ErrAdvanced
+	}
+
+	r := *run
+	m.runs[run.ID] = &r
+
+	return nil
+}
+
+// Cancel cancels a running run
+func (m *Memory) Cancel(_ context.Context, id string) (*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if current.Status != Running {
+		return nil, ErrFinished
+	}
+
+	current.Status = Cancelled
+	current.UpdatedAt = time.Now().UTC()
+
+	r := *current
+
+	return &r, nil
+}
+
+// SaveResult saves the output of a task
+func (m *Memory) SaveResult(_ context.Context, id string, stage, index int, output []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stages, ok := m.results[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	if stages[stage] == nil {
+		stages[stage] = map[int][]byte{}
+	}
+
+	if _, ok := stages[stage][index]; !ok {
+		stages[stage][index] = output
+	}
+
+	return len(stages[stage]), nil
+}
+
+// Results returns the outputs of a stage
+func (m *Memory) Results(_ context.Context, id string, stage int) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outputs := m.results[id][stage]
+	indexes := make([]int, 0, len(outputs))
+
+	for i := range outputs {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	res := make([][]byte, 0, len(indexes))
+
+	for _, i := range indexes {
+		res = append(res, outputs[i])
+	}
+
+	return res, nil
+}
@@ -0,0 +1,256 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "workflow.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	s := NewSQL(db)
+
+	if err := s.CreateTables(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Store{
+		"memory": NewMemory(),
+		"sql":    s,
+	}
+}
+
+func TestStoreAdvance(t *testing.T) {
+	tests := []struct {
+		name string
+		// status and stage of the stored run
+		status Status
+		stage  int
+		from   int
+		err    error
+	}{
+		{name: "at the stage", status: Running, stage: 1, from: 1},
+		{name: "advanced already", status: Running, stage: 2, from: 1, err: ErrAdvanced},
+		{name: "finished", status: Cancelled, stage: 1, from: 1, err: ErrFinished},
+		{name: "unknown", from: 1, err: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		for name, store := range stores(t) {
+			t.Run(tt.name+" in "+name, func(t *testing.T) {
+				ctx := context.Background()
+				now := time.Now().UTC()
+				run := &Run{ID: "run", Workflow: "test", Status: tt.status, Stage: tt.stage, Stages: 3, CreatedAt: now, UpdatedAt: now}
+
+				if tt.status != "" {
+					if err := store.Create(ctx, run); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				next := *run
+				next.Status = Running
+				next.Stage = tt.from + 1
+				next.Width = 4
+
+				if err := store.Advance(ctx, &next, tt.from); !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+
+				if tt.status == "" {
+					return
+				}
+
+				got, err := store.Get(ctx, run.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := run
+				if tt.err == nil {
+					want = &next
+				}
+
+				if got.Status != want.Status || got.Stage != want.Stage || got.Width != want.Width {
+					t.Errorf("run is %s at stage %d of width %d, want %s at stage %d of width %d",
+						got.Status, got.Stage, got.Width, want.Status, want.Stage, want.Width)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreResults(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			if err := store.Create(ctx, &Run{ID: "run", Workflow: "test", Status: Running, Stages: 1, CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+
+			saves := []struct {
+				index  int
+				output string
+				n      int
+			}{
+				{1, `"b"`, 1},
+				{0, `"a"`, 2},
+				// Retried tasks keep the first output
+				{1, `"c"`, 2},
+			}
+
+			for _, s := range saves {
+				n, err := store.SaveResult(ctx, "run", 0, s.index, []byte(s.output))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if n != s.n {
+					t.Errorf("saving %d: got %d outputs, want %d", s.index, n, s.n)
+				}
+			}
+
+			res, err := store.Results(ctx, "run", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != 2 || string(res[0]) != `"a"` || string(res[1]) != `"b"` {
+				t.Errorf("got %q, want [\"a\" \"b\"]", res)
+			}
+		})
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		status Status
+		err    error
+	}{
+		{name: "running", status: Running},
+		{name: "finished", status: Completed, err: ErrFinished},
+		{name: "unknown", err: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		for name, store := range stores(t) {
+			t.Run(tt.name+" in "+name, func(t *testing.T) {
+				ctx := context.Background()
+				now := time.Now().UTC()
+				run := &Run{ID: "run", Workflow: "test", Status: tt.status, Stage: 1, Stages: 3, Width: 1, CreatedAt: now, UpdatedAt: now}
+
+				if tt.status != "" {
+					if err := store.Create(ctx, run); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				// The run advances after it was read to be cancelled
+				if tt.status == Running {
+					next := *run
+					next.Stage = 2
+					next.Width = 4
+
+					if err := store.Advance(ctx, &next, 1); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				cancelled, err := store.Cancel(ctx, run.ID)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+
+				if tt.err != nil {
+					return
+				}
+
+				got, err := store.Get(ctx, run.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, r := range []*Run{cancelled, got} {
+					if r.Status != Cancelled || r.Stage != 2 || r.Width != 4 {
+						t.Errorf("run is %s at stage %d of width %d, want %s at stage 2 of width 4", r.Status, r.Stage, r.Width, Cancelled)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSQLRetention(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "workflow.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewSQL(db, WithSQLRetention(time.Hour))
+
+	if err := s.CreateTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	old := now.Add(-2 * time.Hour)
+
+	runs := []*Run{
+		{ID: "expired", Workflow: "test", Status: Completed, Stages: 1, CreatedAt: old, UpdatedAt: old},
+		{ID: "running", Workflow: "test", Status: Running, Stages: 1, CreatedAt: old, UpdatedAt: old},
+		{ID: "recent", Workflow: "test", Status: Failed, Stages: 1, CreatedAt: now, UpdatedAt: now},
+	}
+
+	for _, run := range runs {
+		if err := s.Create(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.SaveResult(ctx, run.ID, 0, 0, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Finished runs are deleted when another run is created
+	if err := s.Create(ctx, &Run{ID: "new", Workflow: "test", Status: Running, Stages: 1, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		id   string
+		kept bool
+	}{{"expired", false}, {"running", true}, {"recent", true}} {
+		_, err := s.Get(ctx, tt.id)
+		if kept := !errors.Is(err, ErrNotFound); kept != tt.kept {
+			t.Errorf("run %s kept %v, want %v", tt.id, kept, tt.kept)
+		}
+
+		results, err := s.Results(ctx, tt.id, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if kept := len(results) > 0; kept != tt.kept {
+			t.Errorf("results of %s kept %v, want %v", tt.id, kept, tt.kept)
+		}
+	}
+}
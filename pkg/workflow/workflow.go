@@ -0,0 +1,170 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+// Runnable is a step of a workflow, created with NewStep
+type Runnable interface {
+	stepName() string
+	in() reflect.Type
+	out() reflect.Type
+	options() []asynq.Option
+	run(ctx context.Context, input []byte) ([]byte, error)
+}
+
+// Step is a typed step, it receives the output of the previous step as its input
+type Step[In, Out any] struct {
+	name string
+	fn   func(ctx context.Context, in In) (Out, error)
+	opts []asynq.Option
+}
+
+// NewStep defines a step, options apply to the tasks running it, e.g. asynq.MaxRetry.
+// Steps run at least once, like queue handlers they must be safe to run again.
+func NewStep[In, Out any](name string, fn func(ctx context.Context, in In) (Out, error), opts ...asynq.Option) *Step[In, Out] {
+	return &Step[In, Out]{
+		name: name,
+		fn:   fn,
+		opts: opts,
+	}
+}
+
+func (s *Step[In, Out]) stepName() string {
+	return s.name
+}
+
+func (s *Step[In, Out]) in() reflect.Type {
+	return reflect.TypeOf((*In)(nil)).Elem()
+}
+
+func (s *Step[In, Out]) out() reflect.Type {
+	return reflect.TypeOf((*Out)(nil)).Elem()
+}
+
+func (s *Step[In, Out]) options() []asynq.Option {
+	return s.opts
+}
+
+func (s *Step[In, Out]) run(ctx context.Context, input []byte) ([]byte, error) {
+	var in In
+
+	if err := json.Unmarshal(input, &in); err != nil {
+		return nil, fmt.Errorf("decoding %s input: %v: %w", s.name, err, asynq.SkipRetry)
+	}
+
+	out, err := s.fn(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
+
+type stage struct {
+	step   Runnable
+	fanOut bool
+}
+
+// Workflow is a sequence of stages, a stage runs one step or fans a step out over the elements of a slice
+type Workflow struct {
+	name   string
+	opts   []asynq.Option
+	stages []stage
+	output reflect.Type
+}
+
+// New defines a workflow, options apply to the tasks of every step
+func New(name string, opts ...asynq.Option) *Workflow {
+	return &Workflow{
+		name: name,
+		opts: opts,
+	}
+}
+
+// Name returns the name of the workflow
+func (w *Workflow) Name() string {
+	return w.name
+}
+
+// Then runs step with the output of the previous stage, or the input of the workflow for the first stage
+func (w *Workflow) Then(step Runnable) *Workflow {
+	if w.output != nil && w.output != step.in() {
+		log.Fatal().Msgf("Workflow [%s] step [%s] takes %s, the previous stage returns %s", w.name, step.stepName(), step.in(), w.output)
+	}
+
+	w.stages = append(w.stages, stage{step: step})
+	w.output = step.out()
+
+	return w
+}
+
+// FanOut runs step in parallel for every element of the slice the previous stage returns,
+// the next stage receives the outputs as a slice in the same order once all of them succeeded
+func (w *Workflow) FanOut(step Runnable) *Workflow {
+	if w.output != nil && (w.output.Kind() != reflect.Slice || w.output.Elem() != step.in()) {
+		log.Fatal().Msgf("Workflow [%s] fans out [%s] over %s, it takes %s", w.name, step.stepName(), w.output, step.in())
+	}
+
+	w.stages = append(w.stages, stage{step: step, fanOut: true})
+	w.output = reflect.SliceOf(step.out())
+
+	return w
+}
+
+// input returns the input type of the workflow
+func (w *Workflow) input() reflect.Type {
+	if len(w.stages) == 0 {
+		return nil
+	}
+
+	if w.stages[0].fanOut {
+		return reflect.SliceOf(w.stages[0].step.in())
+	}
+
+	return w.stages[0].step.in()
+}
+
+func (w *Workflow) taskType() string {
+	return "workflow:" + w.name
+}
+
+// split returns the inputs of the tasks of a stage
+func (s stage) split(input []byte) ([]json.RawMessage, error) {
+	if !s.fanOut {
+		return []json.RawMessage{input}, nil
+	}
+
+	var inputs []json.RawMessage
+
+	if err := json.Unmarshal(input, &inputs); err != nil {
+		return nil, fmt.Errorf("fanning out %s: %w", s.step.stepName(), err)
+	}
+
+	return inputs, nil
+}
+
+// join returns the output of a stage from the outputs of its tasks
+func (s stage) join(outputs [][]byte) []byte {
+	if !s.fanOut {
+		return outputs[0]
+	}
+
+	res := []byte{'['}
+
+	for i, out := range outputs {
+		if i > 0 {
+			res = append(res, ',')
+		}
+
+		res = append(res, out...)
+	}
+
+	return append(res, ']')
+}